@@ -1,16 +1,29 @@
-package main
+﻿package main
 
 import (
 	"Exchange/internal/config"
+	"Exchange/internal/domain/events"
 	"Exchange/internal/domain/models"
+	"Exchange/internal/fees"
+	"Exchange/internal/gateway"
 	"Exchange/internal/http_client"
+	"Exchange/internal/marketdata"
+	"Exchange/internal/price"
+	"Exchange/internal/risk"
+	"Exchange/internal/services/candles"
+	"Exchange/internal/services/chart"
+	"Exchange/internal/services/funding"
+	"Exchange/internal/services/insurance"
+	"Exchange/internal/services/ledger"
 	"Exchange/internal/services/order"
 	"Exchange/internal/services/trade"
 	user "Exchange/internal/services/user"
 	"Exchange/internal/storage/postgres"
 	"Exchange/internal/storage/redis"
+	"Exchange/internal/ws_client"
 	handler "Exchange/transport"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -18,6 +31,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
 	"time"
 )
 
@@ -67,6 +81,7 @@ func main() {
 	}
 
 	priceClient := http_client.New(*cfg, *log)
+	priceStream := ws_client.New(*cfg, *log)
 	redisClient := redis.New(cfg.RedisCfg)
 
 	// TODO: init NATS CONN
@@ -89,31 +104,85 @@ func main() {
 		log.Error("failed to connect to nats", "error", err)
 		panic(err)
 	}
+	// only latest mark of symbol matters, consumers start from it
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:              "MARK-STREAM",
+		Subjects:          []string{"mark.*"},
+		MaxMsgsPerSubject: 1,
+	})
+	if err != nil {
+		log.Error("failed to add mark stream", "error", err)
+		panic(err)
+	}
+	eventsCfg := &nats.StreamConfig{
+		Name:     events.StreamName,
+		Subjects: events.Subjects,
+	}
+	_, err = js.AddStream(eventsCfg)
+	if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+		// stream of older version has other subjects
+		_, err = js.UpdateStream(eventsCfg)
+	}
+	if err != nil {
+		log.Error("failed to add events stream", "error", err)
+		panic(err)
+	}
 
 	ctx := context.Background()
-	// todo: GET PRICES LOOP
-	go func() {
-		for {
-			prices, _ := priceClient.GetPrice()
-			_ = redisClient.SavePrices(ctx, prices)
-			const topicPart = "prices."
-			for _, priceResp := range prices {
-				// todo: get it from cfg
-				topic := topicPart + priceResp.Symbol
-				_, err = js.Publish(topic, []byte(priceResp.Price))
-				if err != nil {
-					slog.Error("failed to publish price", "topic", topic, "priceResp", priceResp, "err", err)
-				}
+	const (
+		topicPart  = "prices."
+		testTicker = "TESTUSDT"
+	)
+	publishPrices := func(prices []models.PriceResponse) {
+		_ = redisClient.SavePrices(ctx, prices)
+		for _, priceResp := range prices {
+			// todo: get it from cfg
+			topic := topicPart + priceResp.Symbol
+			if _, err := js.Publish(topic, []byte(priceResp.Price)); err != nil {
+				slog.Error("failed to publish price", "topic", topic, "priceResp", priceResp, "err", err)
 			}
-			const testTicker = "TESTUSDT"
-			testPrice, _ := redisClient.GetPrice(ctx, testTicker)
-			testPriceResp := models.PriceResponse{
-				Symbol: testTicker,
-				Price:  testPrice,
+		}
+	}
+	// REST snapshot serves prices until stream delivers first updates
+	if prices, err := priceClient.GetPrice(); err != nil {
+		log.Error("failed to get prices snapshot", "error", err)
+	} else {
+		publishPrices(prices)
+	}
+	go priceStream.Run(ctx, publishPrices)
+	indexAggregator := price.NewAggregator(*log, cfg.IndexCfg,
+		priceClient, http_client.NewBybit(*cfg, *log), http_client.NewOKX(*cfg, *log))
+	go indexAggregator.Run(ctx, func(prices []models.PriceResponse) {
+		if err := redisClient.SaveIndexPrices(ctx, prices); err != nil {
+			log.Error("failed to save index prices", "error", err)
+		}
+	})
+	marketDataGuard := marketdata.NewGuard(cfg.MarketDataCfg)
+	// liquidations and stops are triggered by mark price, trades execute at last price
+	markService := price.NewMark(*log, cfg.MarkCfg, append(slices.Clone(cfg.BinanceConfig.Streams), testTicker),
+		redisClient, redisClient.IndexPrices(), marketDataGuard, cfg.IndexCfg.Interval)
+	go markService.Run(ctx, func(prices []models.PriceResponse) {
+		if err := redisClient.SaveMarkPrices(ctx, prices); err != nil {
+			log.Error("failed to save mark prices", "error", err)
+		}
+		for _, markPrice := range prices {
+			topic := "mark." + markPrice.Symbol
+			if _, err := js.Publish(topic, []byte(markPrice.Price)); err != nil {
+				log.Error("failed to publish mark price", "topic", topic, "price", markPrice.Price, "err", err)
 			}
-			_, err = js.Publish(topicPart+testTicker, []byte(testPriceResp.Price))
-			if err != nil {
-				slog.Error("failed to publish price", "topic", topicPart+testTicker, "priceResp", testPriceResp, "err", err)
+		}
+	})
+	go func() {
+		for {
+			// test price is set by hand, while this feed runs it is quoted now
+			testPrice, err := redisClient.GetPrice(ctx, testTicker)
+			switch {
+			case err != nil:
+				log.Debug("no test price", "ticker", testTicker, "error", err)
+			case testPrice == "":
+				log.Warn("empty test price", "ticker", testTicker)
+			default:
+				publishPrices([]models.PriceResponse{{Symbol: testTicker, Price: testPrice, Time: time.Now()}})
 			}
 			time.Sleep(5 * time.Second)
 		}
@@ -122,23 +191,56 @@ func main() {
 	// TODO: init chi router
 	validate := validator.New()
 
-	userService := user.New(*log, storage, storage)
+	userService := user.New(*log, storage, storage, redisClient, cfg.AuthCfg)
 	orderService := order.New(*log, storage, storage, storage)
-	tradeService := trade.New(log, *orderService, *redisClient)
+	riskModel, err := risk.New(cfg.RiskCfg)
+	if err != nil {
+		log.Error("failed to init risk model", "err", err)
+		os.Exit(1)
+	}
+	feeSchedule, err := fees.New(cfg.FeeCfg)
+	if err != nil {
+		log.Error("failed to init fee schedule", "err", err)
+		os.Exit(1)
+	}
+	tradeService := trade.New(log, *orderService, *redisClient, riskModel, feeSchedule, marketDataGuard)
 
 	//// TODO: init Liquidator
 	//liquidator, err := liquidation.NewLiquidator(nc, orderService)
 	//liquidator.Process()
 
-	userHandler := handler.NewUserHandler(log, userService, validate)
-	tradeHandler := handler.NewTradeHandler(log, tradeService, validate)
+	authMiddleware := handler.NewAuthMiddleware(log, userService)
+	userHandler := handler.NewUserHandler(log, userService, validate, authMiddleware)
+	tradeHandler := handler.NewTradeHandler(log, tradeService, validate, authMiddleware)
+	insuranceService := insurance.New(*log, storage)
+	fundingService := funding.New(*log, cfg.FundingCfg, storage, redisClient.IndexPrices(), redisClient.MarkPrices(),
+		riskModel, redisClient)
+	fundingHandler := handler.NewFundingHandler(log, fundingService, authMiddleware)
+	ledgerService := ledger.New(*log, storage, cfg.LedgerCfg.ReconcileInterval)
+	hub := gateway.NewHub(log, cfg.GatewayCfg)
+	if _, err := hub.Consume(js); err != nil {
+		log.Error("failed to start websocket gateway", "error", err)
+		os.Exit(1)
+	}
+	wsHandler := handler.NewWSHandler(log, hub, userService)
+	streamer := gateway.NewStreamer(log, js, cfg.GatewayCfg)
+	sseHandler := handler.NewSSEHandler(log, streamer, userService, cfg.GatewayCfg.PingInterval)
+	marketDataMonitor := marketdata.NewMonitor(marketDataGuard, append(slices.Clone(cfg.BinanceConfig.Streams), testTicker),
+		redisClient, redisClient.MarkPrices())
+	healthHandler := handler.NewHealthHandler(log, marketDataMonitor)
+	candleService := candles.New(*log, cfg.CandlesCfg, storage, priceClient, cfg.BinanceConfig.Streams)
+	candleHandler := handler.NewCandleHandler(log, candleService)
+	chartService := chart.New(*log, storage, candleService)
+	udfHandler := handler.NewUDFHandler(log, chartService, userService)
+	adminHandler := handler.NewAdminHandler(log, insuranceService, ledgerService, authMiddleware,
+		handler.NewAdminMiddleware(log, cfg.AuthCfg.AdminIDs))
 
 	r := chi.NewRouter()
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Access-Control-Max-Age", "300")
 
@@ -152,6 +254,13 @@ func main() {
 	})
 	r.Mount("/user", userHandler.Routes())
 	r.Mount("/trade", tradeHandler.Routes())
+	r.Mount("/admin", adminHandler.Routes())
+	r.Mount("/funding", fundingHandler.Routes())
+	r.Mount("/ws", wsHandler.Routes())
+	r.Mount("/sse", sseHandler.Routes())
+	r.Mount("/health", healthHandler.Routes())
+	r.Mount("/candles", candleHandler.Routes())
+	r.Mount("/udf", udfHandler.Routes())
 
 	port := ":8080"
 	log.Info("Starting server on " + port)
@@ -1,12 +1,23 @@
 ﻿package main
 
 import (
+	broker "Exchange/internal/brokers/nats"
 	"Exchange/internal/config"
+	"Exchange/internal/fees"
+	"Exchange/internal/http_client"
+	"Exchange/internal/marketdata"
+	"Exchange/internal/risk"
+	"Exchange/internal/services/candles"
+	"Exchange/internal/services/funding"
+	"Exchange/internal/services/ledger"
 	"Exchange/internal/services/order"
+	"Exchange/internal/services/outbox"
 	"Exchange/internal/services/trade"
 	"Exchange/internal/storage/postgres"
 	"Exchange/internal/storage/redis"
+	"Exchange/internal/triggers"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/nats-io/nats.go"
 	"github.com/shopspring/decimal"
@@ -15,6 +26,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -55,7 +67,16 @@ func main() {
 		slog.Error("failed to connect to postgres")
 	}
 	orderService := order.New(*logger, storage, storage, storage)
-	tradeService := trade.New(logger, *orderService, *redis)
+	riskModel, err := risk.New(cfg.RiskCfg)
+	if err != nil {
+		logger.Error("failed to init risk model", "error", err)
+		os.Exit(1)
+	}
+	feeSchedule, err := fees.New(cfg.FeeCfg)
+	if err != nil {
+		logger.Error("failed to init fee schedule", "error", err)
+		os.Exit(1)
+	}
 
 	nc, err := nats.Connect("nats://localhost:4222")
 	if err != nil {
@@ -64,22 +85,33 @@ func main() {
 	}
 	defer nc.Close()
 
+	publisher, err := broker.New(*logger, nc)
+	if err != nil {
+		logger.Error("NATS publisher init failed", "error", err)
+		os.Exit(1)
+	}
+	tradeService := trade.New(logger, *orderService, *redis, riskModel, feeSchedule, marketdata.NewGuard(cfg.MarketDataCfg))
+
 	js, err := nc.JetStream()
 	if err != nil {
 		logger.Error("JetStream init failed", "error", err)
 		os.Exit(1)
 	}
 
+	// ORDER_PROCESSOR of older version liquidated by last price, MARK_LIQUIDATION_PROCESSOR replaced it
+	if err := js.DeleteConsumer("PRICES-STREAM", "ORDER_PROCESSOR"); err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+		logger.Warn("Failed to delete orphaned consumer", "consumer", "ORDER_PROCESSOR", "error", err)
+	}
+
 	const pricesSubj = "prices."
+	// liquidations, stops and margin warnings follow mark price, limit orders fill at last price
+	const markSubj = "mark."
 	// Подписка с правильными опциями
-	sub, err := js.Subscribe(pricesSubj+"*", func(msg *nats.Msg) {
+	sub, err := js.Subscribe(markSubj+"*", func(msg *nats.Msg) {
 		// logger.Info("Received message", "subject", msg.Subject, "body", string(msg.Data))
 
 		// todo: msg handling
-		const quoteAsset = "USDT"
-		key := strings.TrimSuffix(msg.Subject, quoteAsset)
-		key = strings.TrimPrefix(key, pricesSubj)
-		key = key + "/" + quoteAsset
+		key := tickerFromSubject(msg.Subject, markSubj)
 		currentPrice := string(msg.Data)
 		liqOrders, err := redis.GetLiqOrders(ctx, key, currentPrice)
 		if err != nil {
@@ -97,8 +129,8 @@ func main() {
 		}
 		msg.Ack() // Подтверждаем обработку
 	},
-		nats.Durable("ORDER_PROCESSOR"),
-		nats.DeliverAll(),
+		nats.Durable("MARK_LIQUIDATION_PROCESSOR"),
+		nats.DeliverLastPerSubject(),
 		nats.AckExplicit(),
 	)
 	if err != nil {
@@ -107,6 +139,112 @@ func main() {
 	}
 	defer sub.Unsubscribe()
 
+	limitEngine := triggers.NewLimitEngine(logger, redis, tradeService)
+	limitSub, err := js.Subscribe(pricesSubj+"*", func(msg *nats.Msg) {
+		limitEngine.HandlePrice(ctx, tickerFromSubject(msg.Subject, pricesSubj), string(msg.Data))
+		msg.Ack()
+	},
+		nats.Durable("LIMIT_ORDER_PROCESSOR"),
+		nats.DeliverLastPerSubject(),
+		nats.AckExplicit(),
+	)
+	if err != nil {
+		logger.Error("Subscribe failed", "error", err)
+		os.Exit(1)
+	}
+	defer limitSub.Unsubscribe()
+
+	stopEngine := triggers.NewStopEngine(logger, redis, tradeService)
+	stopSub, err := js.Subscribe(markSubj+"*", func(msg *nats.Msg) {
+		stopEngine.HandlePrice(ctx, tickerFromSubject(msg.Subject, markSubj), string(msg.Data))
+		msg.Ack()
+	},
+		nats.Durable("MARK_STOP_ORDER_PROCESSOR"),
+		nats.DeliverLastPerSubject(),
+		nats.AckExplicit(),
+	)
+	if err != nil {
+		logger.Error("Subscribe failed", "error", err)
+		os.Exit(1)
+	}
+	defer stopSub.Unsubscribe()
+
+	trailingEngine := triggers.NewTrailingEngine(logger, redis, tradeService)
+	if err := trailingEngine.Restore(ctx); err != nil {
+		logger.Error("Trailing stops restore failed", "error", err)
+		os.Exit(1)
+	}
+	trailingSub, err := js.Subscribe(markSubj+"*", func(msg *nats.Msg) {
+		trailingEngine.HandlePrice(ctx, tickerFromSubject(msg.Subject, markSubj), string(msg.Data))
+		msg.Ack()
+	},
+		nats.Durable("MARK_TRAILING_STOP_PROCESSOR"),
+		nats.DeliverLastPerSubject(),
+		nats.AckExplicit(),
+	)
+	if err != nil {
+		logger.Error("Subscribe failed", "error", err)
+		os.Exit(1)
+	}
+	defer trailingSub.Unsubscribe()
+
+	marginEngine := triggers.NewMarginEngine(logger, cfg.MarginCfg, redis, orderService, publisher)
+	marginSub, err := js.Subscribe(markSubj+"*", func(msg *nats.Msg) {
+		marginEngine.HandlePrice(ctx, tickerFromSubject(msg.Subject, markSubj), string(msg.Data))
+		msg.Ack()
+	},
+		nats.Durable("MARK_MARGIN_WARNING_PROCESSOR"),
+		nats.DeliverNew(),
+		nats.AckExplicit(),
+	)
+	if err != nil {
+		logger.Error("Subscribe failed", "error", err)
+		os.Exit(1)
+	}
+	defer marginSub.Unsubscribe()
+
+	fundingService := funding.New(*logger, cfg.FundingCfg, storage, redis.IndexPrices(), redis.MarkPrices(),
+		riskModel, redis)
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	go fundingService.Run(workersCtx)
+
+	ledgerService := ledger.New(*logger, storage, cfg.LedgerCfg.ReconcileInterval)
+	go ledgerService.Run(workersCtx)
+
+	// relay holds advisory lock, several consumers don't publish the same events
+	outboxRelay := outbox.New(*logger, cfg.OutboxCfg, storage, publisher)
+	go outboxRelay.Run(workersCtx)
+
+	// bars are built from last prices, history missed while consumer was down comes from Binance klines
+	candleService := candles.New(*logger, cfg.CandlesCfg, storage, http_client.New(*cfg, *logger), cfg.BinanceConfig.Streams)
+	go func() {
+		if err := candleService.Backfill(workersCtx); err != nil {
+			logger.Error("Candles backfill failed", "error", err)
+		}
+	}()
+	candleSub, err := js.Subscribe(pricesSubj+"*", func(msg *nats.Msg) {
+		tickTime := time.Now()
+		if meta, err := msg.Metadata(); err == nil {
+			tickTime = meta.Timestamp
+		}
+		ticker := tickerFromSubject(msg.Subject, pricesSubj)
+		if err := candleService.HandlePrice(ticker, string(msg.Data), tickTime); err != nil {
+			logger.Error("Candle update failed", "ticker", ticker, "error", err)
+		}
+		msg.Ack()
+	},
+		nats.Durable("CANDLE_BUILDER"),
+		nats.DeliverNew(),
+		nats.AckExplicit(),
+	)
+	if err != nil {
+		logger.Error("Subscribe failed", "error", err)
+		os.Exit(1)
+	}
+	defer candleSub.Unsubscribe()
+	go candleService.Run(workersCtx)
+
 	logger.Info("Service started successfully")
 
 	// Ожидание сигнала завершения
@@ -115,3 +253,11 @@ func main() {
 	<-sigChan
 	logger.Info("Shutting down...")
 }
+
+// tickerFromSubject converts prices.BTCUSDT to BTC/USDT
+func tickerFromSubject(subject, pricesSubj string) string {
+	const quoteAsset = "USDT"
+	key := strings.TrimSuffix(subject, quoteAsset)
+	key = strings.TrimPrefix(key, pricesSubj)
+	return key + "/" + quoteAsset
+}
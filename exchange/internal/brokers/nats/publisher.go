@@ -1,6 +1,9 @@
 ﻿package nats
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/nats-io/nats.go"
 	"log/slog"
 )
@@ -10,28 +13,41 @@ type Publisher struct {
 	Js  *nats.JetStreamContext
 }
 
-func New(nc *nats.Conn) (*Publisher, error) {
+func New(log slog.Logger, nc *nats.Conn) (*Publisher, error) {
 	js, err := nc.JetStream()
 	if err != nil {
 		return nil, err
 	}
-	return &Publisher{Js: &js}, nil
+	return &Publisher{log: log, Js: &js}, nil
 }
 
-//
-//func (p *Publisher) Publish(ctx context.Context, subject string, msg interface{}) error {
-//	const op = "nats.Publish"
-//	data, err := json.Marshal(msg)
-//	if err != nil {
-//		p.log.Error("marshalling message", "op", op, "error", err, "msg", msg)
-//		return fmt.Errorf("marshal %T: %w", msg, err)
-//	}
-//	slog.Debug("message published", "msg", msg)
-//
-//
-//	if err != nil {
-//		p.log.Error("publishing message", "op", op, "error", err)
-//		return fmt.Errorf("publishing message: %w", err)
-//	}
-//
-//}
+// Publish marshals msg to json and publishes it to JetStream subject
+func (p *Publisher) Publish(ctx context.Context, subject string, msg any) error {
+	const op = "nats.Publish"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		p.log.Error("marshalling message", "op", op, "error", err, "msg", msg)
+		return fmt.Errorf("marshal %T: %w", msg, err)
+	}
+
+	if _, err := (*p.Js).Publish(subject, data, nats.Context(ctx)); err != nil {
+		p.log.Error("publishing message", "op", op, "error", err, "subject", subject)
+		return fmt.Errorf("publishing message: %w", err)
+	}
+
+	p.log.Debug("message published", "subject", subject)
+	return nil
+}
+
+// PublishMsg publishes raw data with message id, JetStream drops message
+// with id it has already stored within stream's duplicate window
+func (p *Publisher) PublishMsg(ctx context.Context, subject, msgId string, data []byte) error {
+	const op = "nats.PublishMsg"
+	if _, err := (*p.Js).Publish(subject, data, nats.MsgId(msgId), nats.Context(ctx)); err != nil {
+		p.log.Error("publishing message", "op", op, "error", err, "subject", subject, "msg_id", msgId)
+		return fmt.Errorf("publishing message: %w", err)
+	}
+
+	p.log.Debug("message published", "subject", subject, "msg_id", msgId)
+	return nil
+}
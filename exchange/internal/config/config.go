@@ -1,17 +1,60 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"github.com/ilyakaznacheev/cleanenv"
+	"log/slog"
 	"os"
+	"time"
+)
+
+const (
+	envProd = "prod"
+	// placeholderSecret is auth secret of committed config, it is refused in prod
+	placeholderSecret = "dev-secret-change-me"
+	redacted          = "[REDACTED]"
 )
 
 type Config struct {
-	Env            string         `yaml:"env" env-default:"local"`
-	PostgresCfgMac PostgresConfig `yaml:"postgres_mac"`
-	PostgresCfgWin PostgresConfig `yaml:"postgres_win"`
-	RedisCfg       RedisConfig    `yaml:"redis"`
-	BinanceConfig  BinanceConfig  `yaml:"binance_http_client"`
+	Env            string           `yaml:"env" env-default:"local"`
+	PostgresCfgMac PostgresConfig   `yaml:"postgres_mac"`
+	PostgresCfgWin PostgresConfig   `yaml:"postgres_win"`
+	RedisCfg       RedisConfig      `yaml:"redis"`
+	BinanceConfig  BinanceConfig    `yaml:"binance_http_client"`
+	BybitConfig    ExchangeConfig   `yaml:"bybit_http_client"`
+	OKXConfig      ExchangeConfig   `yaml:"okx_http_client"`
+	IndexCfg       IndexConfig      `yaml:"index_price"`
+	MarkCfg        MarkConfig       `yaml:"mark_price"`
+	MarketDataCfg  MarketDataConfig `yaml:"market_data"`
+	AuthCfg        AuthConfig       `yaml:"auth"`
+	RiskCfg        RiskConfig       `yaml:"risk"`
+	FeeCfg         FeeConfig        `yaml:"fees"`
+	FundingCfg     FundingConfig    `yaml:"funding"`
+	LedgerCfg      LedgerConfig     `yaml:"ledger"`
+	MarginCfg      MarginConfig     `yaml:"margin_warning"`
+	GatewayCfg     GatewayConfig    `yaml:"gateway"`
+	OutboxCfg      OutboxConfig     `yaml:"outbox"`
+	CandlesCfg     CandlesConfig    `yaml:"candles"`
+}
+
+// LogValue hides passwords and auth secret, so config can be logged
+func (c Config) LogValue() slog.Value {
+	// plain has no LogValue method, otherwise slog would call it again
+	type plain Config
+	p := plain(c)
+	p.PostgresCfgMac.Password = redact(p.PostgresCfgMac.Password)
+	p.PostgresCfgWin.Password = redact(p.PostgresCfgWin.Password)
+	p.RedisCfg.Password = redact(p.RedisCfg.Password)
+	p.AuthCfg.Secret = redact(p.AuthCfg.Secret)
+	return slog.AnyValue(p)
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
 }
 
 type PostgresConfig struct {
@@ -29,10 +72,134 @@ type RedisConfig struct {
 	Password string `yaml:"password"`
 }
 
+// BinanceConfig: prices of Streams come from WebSocket at WSURL, REST endpoint
+// gives snapshot on start, klines endpoint gives history for candles. Lost stream is redialed after backoff growing
+// from ReconnectMin to ReconnectMax
 type BinanceConfig struct {
-	BaseURL  string   `yaml:"base_url"`
-	Endpoint string   `yaml:"ticker_price_endpoint"`
-	Streams  []string `yaml:"streams"`
+	BaseURL        string        `yaml:"base_url"`
+	Endpoint       string        `yaml:"ticker_price_endpoint"`
+	KlinesEndpoint string        `yaml:"klines_endpoint" env-default:"/api/v3/klines"`
+	Streams        []string      `yaml:"streams"`
+	WSURL          string        `yaml:"ws_url" env-default:"wss://stream.binance.com:9443"`
+	PingInterval   time.Duration `yaml:"ping_interval" env-default:"30s"`
+	ReconnectMin   time.Duration `yaml:"reconnect_min" env-default:"1s"`
+	ReconnectMax   time.Duration `yaml:"reconnect_max" env-default:"1m"`
+	FlushInterval  time.Duration `yaml:"flush_interval" env-default:"250ms"`
+}
+
+// ExchangeConfig is REST tickers endpoint of additional price source,
+// symbols are the same as BinanceConfig.Streams
+type ExchangeConfig struct {
+	BaseURL  string `yaml:"base_url"`
+	Endpoint string `yaml:"ticker_price_endpoint"`
+}
+
+// IndexConfig: index price is median of sources polled every Interval.
+// Quotes farther than MaxDeviation (fraction of median) from median are dropped,
+// ticker with less than MinSources quotes left gets no index price
+type IndexConfig struct {
+	Interval     time.Duration `yaml:"interval" env-default:"5s"`
+	MaxDeviation float64       `yaml:"max_deviation" env-default:"0.02"`
+	MinSources   int           `yaml:"min_sources" env-default:"2"`
+}
+
+// MarkConfig: every Interval mark price is index price plus basis (last − index)
+// smoothed by EMA over BasisPeriod samples
+type MarkConfig struct {
+	Interval    time.Duration `yaml:"interval" env-default:"1s"`
+	BasisPeriod int           `yaml:"basis_period" env-default:"30"`
+}
+
+// MarketDataConfig: price quoted earlier than MaxPriceAge ago (PairMaxPriceAge
+// for pair listed there) is stale, pair with stale price is not traded or liquidated
+type MarketDataConfig struct {
+	MaxPriceAge     time.Duration            `yaml:"max_price_age" env-default:"15s"`
+	PairMaxPriceAge map[string]time.Duration `yaml:"pair_max_price_age"`
+}
+
+// AuthConfig: Secret signs JWT. It can't be empty, in prod it must be set
+// with AUTH_SECRET instead of committed placeholder
+type AuthConfig struct {
+	Secret     string        `yaml:"secret" env:"AUTH_SECRET"`
+	AccessTTL  time.Duration `yaml:"access_ttl" env-default:"15m"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+	AdminIDs   []int64       `yaml:"admin_ids"`
+}
+
+type RiskConfig struct {
+	LiquidationFeeRate float64                   `yaml:"liquidation_fee_rate"`
+	Brackets           []BracketConfig           `yaml:"brackets"`
+	Pairs              map[string]RiskPairConfig `yaml:"pairs"`
+}
+
+// RiskPairConfig overrides default brackets and fee for ticker (BTC/USDT)
+type RiskPairConfig struct {
+	LiquidationFeeRate float64         `yaml:"liquidation_fee_rate"`
+	Brackets           []BracketConfig `yaml:"brackets"`
+}
+
+// BracketConfig is maintenance margin tier for position notional up to NotionalCap
+type BracketConfig struct {
+	NotionalCap float64 `yaml:"notional_cap"`
+	MaxLeverage uint8   `yaml:"max_leverage"`
+	MMR         float64 `yaml:"mmr"`
+}
+
+type FeeConfig struct {
+	Tiers []FeeTierConfig            `yaml:"tiers"`
+	Pairs map[string][]FeeTierConfig `yaml:"pairs"`
+}
+
+// FeeTierConfig applies to users with 30-day volume of at least MinVolume
+type FeeTierConfig struct {
+	MinVolume float64 `yaml:"min_volume"`
+	Maker     float64 `yaml:"maker"`
+	Taker     float64 `yaml:"taker"`
+}
+
+// FundingConfig sets funding rate = premium + clamp(interest - premium, ±PremiumClamp),
+// capped by MaxRate. Tickers from FixedRates are charged their configured rate instead
+type FundingConfig struct {
+	Interval     time.Duration      `yaml:"interval" env-default:"8h"`
+	InterestRate float64            `yaml:"interest_rate"`
+	PremiumClamp float64            `yaml:"premium_clamp"`
+	MaxRate      float64            `yaml:"max_rate"`
+	FixedRates   map[string]float64 `yaml:"fixed_rates"`
+}
+
+type LedgerConfig struct {
+	ReconcileInterval time.Duration `yaml:"reconcile_interval" env-default:"10m"`
+}
+
+// MarginConfig: margin warning is sent once per Cooldown when price comes
+// within Threshold (fraction of price) of position's liquidation price
+type MarginConfig struct {
+	Threshold float64       `yaml:"threshold" env-default:"0.05"`
+	Cooldown  time.Duration `yaml:"cooldown" env-default:"5m"`
+}
+
+// GatewayConfig configures WebSocket gateway. Client whose SendBuffer
+// overflows is disconnected, so slow clients can't stall price fan-out.
+// SSE client which doesn't take user event within EventTimeout is disconnected too
+type GatewayConfig struct {
+	SendBuffer       int           `yaml:"send_buffer" env-default:"256"`
+	PingInterval     time.Duration `yaml:"ping_interval" env-default:"30s"`
+	MaxSubscriptions int           `yaml:"max_subscriptions" env-default:"50"`
+	EventTimeout     time.Duration `yaml:"event_timeout" env-default:"10s"`
+}
+
+// OutboxConfig: relay publishes up to BatchSize outbox messages every Interval,
+// full batch is followed by next one right away
+type OutboxConfig struct {
+	Interval  time.Duration `yaml:"interval" env-default:"1s"`
+	BatchSize int           `yaml:"batch_size" env-default:"100"`
+}
+
+// CandlesConfig: bars built from price ticks are saved every FlushInterval.
+// On start bars of last BackfillPeriod missing in storage are loaded from Binance
+type CandlesConfig struct {
+	FlushInterval  time.Duration `yaml:"flush_interval" env-default:"1s"`
+	BackfillPeriod time.Duration `yaml:"backfill_period" env-default:"168h"`
 }
 
 func MustLoad() *Config {
@@ -50,6 +217,9 @@ func MustLoad() *Config {
 	if err := cleanenv.ReadConfig(path, &config); err != nil {
 		panic("failed to read config " + err.Error())
 	}
+	if err := config.validate(); err != nil {
+		panic("invalid config " + err.Error())
+	}
 
 	return &config
 }
@@ -64,10 +234,24 @@ func MustLoadByPath(path string) *Config {
 	if err := cleanenv.ReadConfig(path, &config); err != nil {
 		panic("failed to read config " + err.Error())
 	}
+	if err := config.validate(); err != nil {
+		panic("invalid config " + err.Error())
+	}
 
 	return &config
 }
 
+// validate refuses config app must not start with
+func (c *Config) validate() error {
+	if c.AuthCfg.Secret == "" {
+		return errors.New("auth secret is empty")
+	}
+	if c.Env == envProd && c.AuthCfg.Secret == placeholderSecret {
+		return errors.New("auth secret is placeholder, set AUTH_SECRET")
+	}
+	return nil
+}
+
 func fetchConfigPath() string {
 	var res string
 
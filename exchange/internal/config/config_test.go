@@ -0,0 +1,61 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		secret  string
+		wantErr bool
+	}{
+		{name: "dev placeholder", env: "dev_win", secret: placeholderSecret},
+		{name: "prod secret", env: envProd, secret: "4f1c0e7d9a2b"},
+		{name: "dev empty", env: "dev_win", wantErr: true},
+		{name: "prod empty", env: envProd, wantErr: true},
+		{name: "prod placeholder", env: envProd, secret: placeholderSecret, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Env: tt.env, AuthCfg: AuthConfig{Secret: tt.secret}}
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogValueHidesSecrets(t *testing.T) {
+	cfg := &Config{
+		Env:            "dev_win",
+		PostgresCfgMac: PostgresConfig{Host: "localhost", Password: "pg-mac-password"},
+		PostgresCfgWin: PostgresConfig{Host: "localhost", Password: "pg-win-password"},
+		RedisCfg:       RedisConfig{Host: "localhost", Password: "redis-password"},
+		AuthCfg:        AuthConfig{Secret: "jwt-secret"},
+	}
+
+	for name, handler := range map[string]func(*bytes.Buffer) slog.Handler{
+		"json": func(b *bytes.Buffer) slog.Handler { return slog.NewJSONHandler(b, nil) },
+		"text": func(b *bytes.Buffer) slog.Handler { return slog.NewTextHandler(b, nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			slog.New(handler(&out)).Info("starting", slog.Any("cfg", cfg))
+
+			for _, secret := range []string{"pg-mac-password", "pg-win-password", "redis-password", "jwt-secret"} {
+				if strings.Contains(out.String(), secret) {
+					t.Errorf("log contains %q: %s", secret, out.String())
+				}
+			}
+			if !strings.Contains(out.String(), redacted) || !strings.Contains(out.String(), "localhost") {
+				t.Errorf("log has no redacted config: %s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package events describes domain events published to EVENTS-STREAM,
+// subject of event is its type and owner: orders.opened.42, balance.changed.42.
+// Payload of type is versioned: incompatible change of payload bumps its version,
+// so consumers can skip or migrate events of version they don't know
+package events
+
+import (
+	"Exchange/internal/domain/models"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+const (
+	StreamName = "EVENTS-STREAM"
+)
+
+// Subjects are subjects of EVENTS-STREAM. Events published before owner was added
+// to subject (orders.opened) match them too
+var Subjects = []string{"orders.>", "balance.>", "margin.>"}
+
+type Type string
+
+const (
+	OrderOpened     Type = "orders.opened"
+	OrderChanged    Type = "orders.changed"
+	OrderClosed     Type = "orders.closed"
+	OrderLiquidated Type = "orders.liquidated"
+	BalanceChanged  Type = "balance.changed"
+	MarginWarning   Type = "margin.warning"
+)
+
+// versions are current payload versions of event types
+var versions = map[Type]int{
+	OrderOpened:     1,
+	OrderChanged:    1,
+	OrderClosed:     1,
+	OrderLiquidated: 1,
+	BalanceChanged:  1,
+	MarginWarning:   1,
+}
+
+// Version returns current payload version of event type, zero for unknown type
+func Version(eventType Type) int {
+	return versions[eventType]
+}
+
+// Event is envelope of every domain event, Data holds payload of Type in Version
+type Event struct {
+	Id      uuid.UUID       `json:"id"`
+	Type    Type            `json:"type"`
+	Version int             `json:"version"`
+	UserId  int64           `json:"user_id"`
+	Time    time.Time       `json:"time"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Subject is JetStream subject event is published to
+func (e Event) Subject() string {
+	return fmt.Sprintf("%s.%d", e.Type, e.UserId)
+}
+
+// UserSubject matches subjects of every event of user
+func UserSubject(userId int64) string {
+	return fmt.Sprintf("*.*.%d", userId)
+}
+
+// Order is payload of orders.* events
+type Order struct {
+	OrderId          uuid.UUID          `json:"order_id"`
+	Ticker           string             `json:"ticker"`
+	Type             models.OrderType   `json:"type"`
+	Status           models.OrderStatus `json:"status"`
+	Margin           decimal.Decimal    `json:"margin"`
+	Leverage         uint8              `json:"leverage"`
+	EntryPrice       decimal.Decimal    `json:"entry_price"`
+	ClosePrice       *decimal.Decimal   `json:"close_price,omitempty"`
+	LiquidationPrice decimal.Decimal    `json:"liquidation_price"`
+}
+
+// Balance is payload of balance.changed
+type Balance struct {
+	Balance decimal.Decimal `json:"balance"`
+}
+
+// Margin is payload of margin.warning, sent when price comes close to liquidation price
+type Margin struct {
+	OrderId          uuid.UUID        `json:"order_id"`
+	Ticker           string           `json:"ticker"`
+	Type             models.OrderType `json:"type"`
+	Price            decimal.Decimal  `json:"price"`
+	LiquidationPrice decimal.Decimal  `json:"liquidation_price"`
+}
+
+func New(eventType Type, userId int64, data any) (Event, error) {
+	version := Version(eventType)
+	if version == 0 {
+		return Event{}, fmt.Errorf("unknown event type %s", eventType)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("marshal %s payload: %w", eventType, err)
+	}
+
+	return Event{
+		Id:      uuid.New(),
+		Type:    eventType,
+		Version: version,
+		UserId:  userId,
+		Time:    time.Now().UTC(),
+		Data:    raw,
+	}, nil
+}
+
+// OrderOf builds orders.* payload from order
+func OrderOf(order models.Order) Order {
+	return Order{
+		OrderId:          order.Id,
+		Ticker:           strings.TrimSpace(order.Ticker),
+		Type:             order.Type,
+		Status:           order.Status,
+		Margin:           order.Margin.Add(order.ExtraMargin),
+		Leverage:         order.Leverage,
+		EntryPrice:       order.EntryPrice,
+		ClosePrice:       order.ClosePrice,
+		LiquidationPrice: order.LiquidationPrice,
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Candle is OHLCV bar of ticker (BTC/USDT) opened at OpenTime and lasting Interval (1m, 1h, ...)
+type Candle struct {
+	Ticker   string          `json:"-"`
+	Interval string          `json:"-"`
+	OpenTime time.Time       `json:"open_time"`
+	Open     decimal.Decimal `json:"open"`
+	High     decimal.Decimal `json:"high"`
+	Low      decimal.Decimal `json:"low"`
+	Close    decimal.Decimal `json:"close"`
+	Volume   decimal.Decimal `json:"volume"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type ChartMarkKind string
+
+const (
+	MarkEntry       ChartMarkKind = "entry"
+	MarkExit        ChartMarkKind = "exit"
+	MarkLiquidation ChartMarkKind = "liquidation"
+)
+
+// ChartMark is user's entry, exit (full or partial close) or liquidation shown on chart
+type ChartMark struct {
+	OrderId uuid.UUID
+	Kind    ChartMarkKind
+	Type    OrderType
+	Price   decimal.Decimal
+	Time    time.Time
+}
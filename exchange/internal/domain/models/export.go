@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// OrderExport is one row of user's order history export
+type OrderExport struct {
+	Id          uuid.UUID        `json:"id"`
+	Ticker      string           `json:"ticker"`
+	Type        OrderType        `json:"type"`
+	Status      OrderStatus      `json:"status"`
+	Leverage    uint8            `json:"leverage"`
+	Margin      decimal.Decimal  `json:"margin"`
+	ExtraMargin decimal.Decimal  `json:"extra_margin"`
+	EntryPrice  decimal.Decimal  `json:"entry_price"`
+	ClosePrice  *decimal.Decimal `json:"close_price"`
+	// RealizedPnl sums closes of position, liquidation realizes whole locked margin as loss
+	RealizedPnl decimal.Decimal `json:"realized_pnl"`
+	// Fees sums open, close and liquidation fees
+	Fees      decimal.Decimal `json:"fees"`
+	Funding   decimal.Decimal `json:"funding"`
+	CreatedAt time.Time       `json:"created_at"`
+	// ClosedAt is nil while position is open
+	ClosedAt *time.Time `json:"closed_at"`
+}
+
+// OrderExportFilter narrows exported orders, zero values don't filter
+type OrderExportFilter struct {
+	Ticker string
+	// From is inclusive, To is exclusive
+	From time.Time
+	To   time.Time
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type FeeKind string
+
+const (
+	OpenFee  FeeKind = "open"
+	CloseFee FeeKind = "close"
+)
+
+// Liquidity is maker for limit orders resting in the book and taker for market orders
+type Liquidity string
+
+const (
+	Maker Liquidity = "maker"
+	Taker Liquidity = "taker"
+)
+
+// OrderFee is fee charged on opening or (partially) closing position
+type OrderFee struct {
+	Id        int64           `json:"id"`
+	OrderId   uuid.UUID       `json:"order_id"`
+	UserId    int64           `json:"-"`
+	Kind      FeeKind         `json:"kind"`
+	Liquidity Liquidity       `json:"liquidity"`
+	Notional  decimal.Decimal `json:"notional"`
+	Rate      decimal.Decimal `json:"rate"`
+	Amount    decimal.Decimal `json:"amount"`
+	CreatedAt time.Time       `json:"created_at"`
+}
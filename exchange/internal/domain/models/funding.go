@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// FundingPayment is funding received (positive Amount) or paid (negative) by open position
+type FundingPayment struct {
+	Id        int64           `json:"id"`
+	OrderId   uuid.UUID       `json:"order_id"`
+	UserId    int64           `json:"-"`
+	Ticker    string          `json:"ticker"`
+	Rate      decimal.Decimal `json:"rate"`
+	MarkPrice decimal.Decimal `json:"mark_price"`
+	Notional  decimal.Decimal `json:"notional"`
+	Amount    decimal.Decimal `json:"amount"`
+	// FromMargin is part of paid Amount taken from position's extra margin after balance ran out
+	FromMargin decimal.Decimal `json:"from_margin"`
+	// Shortfall is part of payment neither balance nor extra margin covered, insurance fund pays it
+	Shortfall   decimal.Decimal `json:"shortfall"`
+	FundingTime time.Time       `json:"funding_time"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type InsuranceReason string
+
+const (
+	// InsuranceLiquidationFee is liquidation fee charged from liquidated position
+	InsuranceLiquidationFee InsuranceReason = "liquidation_fee"
+	// InsuranceSurplus is margin left after position was liquidated above bankruptcy price
+	InsuranceSurplus InsuranceReason = "liquidation_surplus"
+	// InsuranceBankruptcyLoss is loss of position liquidated below bankruptcy price
+	InsuranceBankruptcyLoss InsuranceReason = "bankruptcy_loss"
+	// InsuranceFundingShortfall is funding payment position couldn't cover, fund pays it to receivers
+	InsuranceFundingShortfall InsuranceReason = "funding_shortfall"
+)
+
+// InsuranceFund covers losses of positions liquidated past their bankruptcy price
+type InsuranceFund struct {
+	Balance   decimal.Decimal `json:"balance"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// InsuranceFundEntry is a single change of insurance fund balance
+type InsuranceFundEntry struct {
+	Id        int64           `json:"id"`
+	OrderId   *uuid.UUID      `json:"order_id,omitempty"`
+	Reason    InsuranceReason `json:"reason"`
+	Amount    decimal.Decimal `json:"amount"`
+	Balance   decimal.Decimal `json:"balance"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// LedgerAccount is account of double-entry ledger. User accounts are kept per user_id
+type LedgerAccount string
+
+const (
+	// AccountWallet mirrors users.balance
+	AccountWallet LedgerAccount = "user_wallet"
+	// AccountMargin holds margin locked in orders
+	AccountMargin LedgerAccount = "user_margin"
+	// AccountExternal is counterparty of deposits and withdrawals
+	AccountExternal LedgerAccount = "external"
+	// AccountExchangePnl is counterparty of traders' realized pnl
+	AccountExchangePnl LedgerAccount = "exchange_pnl"
+	AccountFees        LedgerAccount = "exchange_fees"
+	AccountFunding     LedgerAccount = "funding_pool"
+	AccountInsurance   LedgerAccount = "insurance_fund"
+)
+
+type LedgerEntryKind string
+
+const (
+	LedgerOpeningBalance LedgerEntryKind = "opening_balance"
+	LedgerDeposit        LedgerEntryKind = "deposit"
+	LedgerWithdrawal     LedgerEntryKind = "withdrawal"
+	LedgerMarginLock     LedgerEntryKind = "margin_lock"
+	LedgerMarginRelease  LedgerEntryKind = "margin_release"
+	LedgerRealizedPnl    LedgerEntryKind = "realized_pnl"
+	LedgerFee            LedgerEntryKind = "fee"
+	LedgerFunding        LedgerEntryKind = "funding"
+	LedgerLiquidation    LedgerEntryKind = "liquidation"
+)
+
+// LedgerEntry is one side of journal, positive Amount increases account balance
+type LedgerEntry struct {
+	Id        int64           `json:"id"`
+	JournalId int64           `json:"journal_id"`
+	Kind      LedgerEntryKind `json:"kind"`
+	Account   LedgerAccount   `json:"account"`
+	UserId    *int64          `json:"user_id,omitempty"`
+	OrderId   *uuid.UUID      `json:"order_id,omitempty"`
+	Amount    decimal.Decimal `json:"amount"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type DriftKind string
+
+const (
+	// DriftBalance is users.balance not equal to sum of user's wallet entries
+	DriftBalance DriftKind = "balance"
+	// DriftJournal is journal whose entries don't sum to zero
+	DriftJournal DriftKind = "journal"
+)
+
+// LedgerDrift is inconsistency found by reconciliation
+type LedgerDrift struct {
+	Id         int64           `json:"id"`
+	Kind       DriftKind       `json:"kind"`
+	UserId     *int64          `json:"user_id,omitempty"`
+	JournalId  *int64          `json:"journal_id,omitempty"`
+	Expected   decimal.Decimal `json:"expected"`
+	Actual     decimal.Decimal `json:"actual"`
+	DetectedAt time.Time       `json:"detected_at"`
+}
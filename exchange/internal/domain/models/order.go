@@ -20,6 +20,7 @@ const (
 	Closed     OrderStatus = "closed"
 	Liquidated OrderStatus = "liquidated"
 	Canceled   OrderStatus = "canceled"
+	Pending    OrderStatus = "pending"
 )
 
 type Order struct {
@@ -35,4 +36,67 @@ type Order struct {
 	CreatedAt        time.Time
 	LiquidationPrice decimal.Decimal
 	Ticker           string
+	LimitPrice       *decimal.Decimal
+	StopLoss         *decimal.Decimal
+	TakeProfit       *decimal.Decimal
+	TrailingStop     *TrailingStop
+	// ExtraMargin is isolated margin added on top of Margin. It moves liquidation
+	// price away but does not change position size (Margin * Leverage)
+	ExtraMargin decimal.Decimal
+	// LiquidationFee is charged from remaining equity of liquidated position
+	LiquidationFee decimal.Decimal
+	// OpenFee and CloseFee are trading fees paid for the order, CloseFee sums partial closes
+	OpenFee  decimal.Decimal
+	CloseFee decimal.Decimal
+	// Funding is sum of funding payments, positive when position received more than paid
+	Funding decimal.Decimal
+}
+
+// Stops are optional exit levels attached to position
+type Stops struct {
+	StopLoss   *decimal.Decimal
+	TakeProfit *decimal.Decimal
+}
+
+func (o Order) Stops() Stops {
+	return Stops{StopLoss: o.StopLoss, TakeProfit: o.TakeProfit}
+}
+
+type TrailingType string
+
+const (
+	TrailingPercent  TrailingType = "percent"
+	TrailingAbsolute TrailingType = "absolute"
+)
+
+// TrailingStop follows the best price reached by position (Extreme)
+// and fires when price pulls back from it by Value
+type TrailingStop struct {
+	Type    TrailingType
+	Value   decimal.Decimal
+	Extreme decimal.Decimal
+}
+
+// Distance returns pullback size in quote asset
+func (ts TrailingStop) Distance() decimal.Decimal {
+	if ts.Type == TrailingPercent {
+		return ts.Extreme.Mul(ts.Value).Div(decimal.NewFromInt(100))
+	}
+	return ts.Value
+}
+
+// Level returns price that closes position
+func (ts TrailingStop) Level(orderType OrderType) decimal.Decimal {
+	if orderType == Long {
+		return ts.Extreme.Sub(ts.Distance())
+	}
+	return ts.Extreme.Add(ts.Distance())
+}
+
+// Improves reports whether price is better than extreme for position
+func (ts TrailingStop) Improves(orderType OrderType, price decimal.Decimal) bool {
+	if orderType == Long {
+		return price.GreaterThan(ts.Extreme)
+	}
+	return price.LessThan(ts.Extreme)
 }
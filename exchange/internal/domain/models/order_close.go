@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// OrderClose is a partial close of position
+type OrderClose struct {
+	Id          int64           `json:"id"`
+	OrderId     uuid.UUID       `json:"order_id"`
+	Margin      decimal.Decimal `json:"margin"`
+	ClosePrice  decimal.Decimal `json:"close_price"`
+	RealizedPnl decimal.Decimal `json:"realized_pnl"`
+	Fee         decimal.Decimal `json:"fee"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
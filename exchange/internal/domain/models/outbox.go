@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// OutboxMessage is domain event waiting in outbox to be published,
+// EventId is used as JetStream message id to drop duplicates of redelivery
+type OutboxMessage struct {
+	Id        int64
+	EventId   uuid.UUID
+	Subject   string
+	Payload   []byte
+	CreatedAt time.Time
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PortfolioPosition is open position valued at current price
+type PortfolioPosition struct {
+	OrderId  uuid.UUID `json:"order_id"`
+	Ticker   string    `json:"ticker"`
+	Type     OrderType `json:"type"`
+	Leverage uint8     `json:"leverage"`
+	// Margin includes extra margin
+	Margin     decimal.Decimal `json:"margin"`
+	Size       decimal.Decimal `json:"size"`
+	EntryPrice decimal.Decimal `json:"entry_price"`
+	// CurrentPrice is last price pnl is valued at
+	CurrentPrice decimal.Decimal `json:"current_price"`
+	// MarkPrice is price position is liquidated by
+	MarkPrice        decimal.Decimal `json:"mark_price"`
+	LiquidationPrice decimal.Decimal `json:"liquidation_price"`
+	UnrealizedPnl    decimal.Decimal `json:"unrealized_pnl"`
+	// Roe is unrealized pnl in percent of margin
+	Roe decimal.Decimal `json:"roe"`
+	// LiquidationDistance is how far mark price is from liquidation price, in percent
+	LiquidationDistance decimal.Decimal `json:"liquidation_distance"`
+	// MarginRatio is maintenance margin in percent of equity at mark price, position is liquidated at 100
+	MarginRatio decimal.Decimal `json:"margin_ratio"`
+	// Unpriced is set when ticker has no price yet, position is not valued then
+	Unpriced bool `json:"unpriced"`
+}
+
+// Portfolio is user's account valued at current prices. Margin of open and pending
+// orders is already taken from Balance, so whole Balance is available for new orders
+type Portfolio struct {
+	Balance          decimal.Decimal     `json:"balance"`
+	AvailableBalance decimal.Decimal     `json:"available_balance"`
+	UsedMargin       decimal.Decimal     `json:"used_margin"`
+	UnrealizedPnl    decimal.Decimal     `json:"unrealized_pnl"`
+	Equity           decimal.Decimal     `json:"equity"`
+	Positions        []PortfolioPosition `json:"positions"`
+}
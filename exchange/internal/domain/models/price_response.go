@@ -1,6 +1,25 @@
 package models
 
+import "time"
+
 type PriceResponse struct {
 	Symbol string `json:"symbol"`
 	Price  string `json:"price"`
+	// Time is when source quoted price, zero if source doesn't tell
+	Time time.Time `json:"-"`
+}
+
+// Quote is stored price with time it was quoted at, zero time is unknown
+type Quote struct {
+	Price string    `json:"price"`
+	Time  time.Time `json:"time"`
+}
+
+// PairHealth is freshness of pair's prices, trading of stale pair is halted
+type PairHealth struct {
+	Ticker        string     `json:"ticker"`
+	LastPriceTime *time.Time `json:"last_price_time"`
+	MarkPriceTime *time.Time `json:"mark_price_time"`
+	MaxAgeSeconds float64    `json:"max_age_seconds"`
+	Stale         bool       `json:"stale"`
 }
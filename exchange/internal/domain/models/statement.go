@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Transaction is movement of user's wallet with wallet balance right after it
+type Transaction struct {
+	Id        int64           `json:"id"`
+	JournalId int64           `json:"journal_id"`
+	Kind      LedgerEntryKind `json:"type"`
+	OrderId   *uuid.UUID      `json:"order_id,omitempty"`
+	Amount    decimal.Decimal `json:"amount"`
+	Balance   decimal.Decimal `json:"balance"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// TransactionFilter narrows transaction history, zero values don't filter
+type TransactionFilter struct {
+	Kinds []LedgerEntryKind
+	// From is inclusive, To is exclusive
+	From time.Time
+	To   time.Time
+}
+
+// Statement summarizes user's wallet over [From, To):
+// ClosingBalance = OpeningBalance + Deposits - Withdrawals + RealizedPnl - Fees + Funding - MarginLocked + MarginReleased.
+// LiquidationLoss is margin lost in liquidations, it never comes back to wallet
+type Statement struct {
+	From            time.Time       `json:"from"`
+	To              time.Time       `json:"to"`
+	OpeningBalance  decimal.Decimal `json:"opening_balance"`
+	ClosingBalance  decimal.Decimal `json:"closing_balance"`
+	Deposits        decimal.Decimal `json:"deposits"`
+	Withdrawals     decimal.Decimal `json:"withdrawals"`
+	RealizedPnl     decimal.Decimal `json:"realized_pnl"`
+	Fees            decimal.Decimal `json:"fees"`
+	Funding         decimal.Decimal `json:"funding"`
+	MarginLocked    decimal.Decimal `json:"margin_locked"`
+	MarginReleased  decimal.Decimal `json:"margin_released"`
+	LiquidationLoss decimal.Decimal `json:"liquidation_loss"`
+}
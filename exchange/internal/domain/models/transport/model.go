@@ -22,17 +22,11 @@ type GetBalanceRequest struct {
 	Id int64 `json:"id" validate:"required"`
 }
 
-type BalanceRequest struct {
-	UserID int64 `json:"id" validate:"required,gt=0"`
-}
-
 type IncreaseBalanceRequest struct {
-	Id     int64           `json:"id" validate:"required,gt=0"`
 	Amount decimal.Decimal `json:"amount" validate:"required"`
 }
 
 type DecreaseBalanceRequest struct {
-	Id     int64           `json:"id" validate:"required,gt=0"`
 	Amount decimal.Decimal `json:"amount" validate:"required"`
 }
 
@@ -42,28 +36,85 @@ type BalanceResponse struct {
 }
 
 type OpenTradeRequest struct {
-	UserID    int64            `json:"user_id" validate:"required"`
-	Ticker    string           `json:"ticker" validate:"required"`
-	OrderType models.OrderType `json:"order_type" validate:"required"`
-	Margin    decimal.Decimal  `json:"margin" validate:"required"`
-	Leverage  uint8            `json:"leverage" validate:"required"`
+	Ticker     string           `json:"ticker" validate:"required"`
+	OrderType  models.OrderType `json:"order_type" validate:"required"`
+	Margin     decimal.Decimal  `json:"margin" validate:"required"`
+	Leverage   uint8            `json:"leverage" validate:"required"`
+	StopLoss   *decimal.Decimal `json:"stop_loss,omitempty"`
+	TakeProfit *decimal.Decimal `json:"take_profit,omitempty"`
 }
 
 type OpenTradeResponse struct {
 	OrderID uuid.UUID `json:"order_id"`
 }
 
+type PlaceLimitOrderRequest struct {
+	Ticker     string           `json:"ticker" validate:"required"`
+	OrderType  models.OrderType `json:"order_type" validate:"required"`
+	Margin     decimal.Decimal  `json:"margin" validate:"required"`
+	Leverage   uint8            `json:"leverage" validate:"required"`
+	LimitPrice decimal.Decimal  `json:"limit_price" validate:"required"`
+	StopLoss   *decimal.Decimal `json:"stop_loss,omitempty"`
+	TakeProfit *decimal.Decimal `json:"take_profit,omitempty"`
+}
+
+type UpdateStopsRequest struct {
+	OrderID    uuid.UUID        `json:"order_id" validate:"required"`
+	StopLoss   *decimal.Decimal `json:"stop_loss"`
+	TakeProfit *decimal.Decimal `json:"take_profit"`
+}
+
+type CancelOrderRequest struct {
+	OrderID uuid.UUID `json:"order_id" validate:"required"`
+}
+
+type TrailingStopRequest struct {
+	OrderID uuid.UUID           `json:"order_id" validate:"required"`
+	Type    models.TrailingType `json:"type" validate:"omitempty,oneof=percent absolute"`
+	Value   decimal.Decimal     `json:"value"`
+}
+
 type CloseTradeRequest struct {
 	OrderID uuid.UUID `json:"order_id" validate:"required"`
-	Ticker  string    `json:"ticker" validate:"required"`
 }
 
 type CloseTradeResponse struct {
 	OrderID uuid.UUID `json:"order_id"`
 }
 
-type GetOrdersRequest struct {
-	Id int64 `json:"id" validate:"required"`
+type PartialCloseRequest struct {
+	OrderID  uuid.UUID       `json:"order_id" validate:"required"`
+	Fraction decimal.Decimal `json:"fraction"`
+	Notional decimal.Decimal `json:"notional"`
+}
+
+type PartialCloseResponse struct {
+	OrderID         uuid.UUID       `json:"order_id"`
+	ClosedMargin    decimal.Decimal `json:"closed_margin"`
+	ClosePrice      decimal.Decimal `json:"close_price"`
+	RealizedPnl     decimal.Decimal `json:"realized_pnl"`
+	Fee             decimal.Decimal `json:"fee"`
+	RemainingMargin decimal.Decimal `json:"remaining_margin"`
+}
+
+type MarginRequest struct {
+	OrderID uuid.UUID       `json:"order_id" validate:"required"`
+	Amount  decimal.Decimal `json:"amount" validate:"required"`
+}
+
+type MarginResponse struct {
+	OrderID          uuid.UUID       `json:"order_id"`
+	Margin           decimal.Decimal `json:"margin"`
+	ExtraMargin      decimal.Decimal `json:"extra_margin"`
+	LiquidationPrice decimal.Decimal `json:"liquidation_price"`
+}
+
+type GetOrderClosesResponse struct {
+	Closes []models.OrderClose `json:"closes"`
+}
+
+type GetOrderFeesResponse struct {
+	Fees []models.OrderFee `json:"fees"`
 }
 
 type GetOrdersResponse struct {
@@ -78,4 +129,125 @@ type LoginRequest struct {
 type LoginResponse struct {
 	Id    int64  `json:"id"`
 	Email string `json:"email"`
+	models.TokenPair
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type InsuranceFundResponse struct {
+	models.InsuranceFund
+	History []models.InsuranceFundEntry `json:"history"`
+}
+
+type LedgerDriftsResponse struct {
+	Drifts []models.LedgerDrift `json:"drifts"`
+}
+
+type FundingPaymentsResponse struct {
+	Payments []models.FundingPayment `json:"payments"`
+}
+
+type TransactionsResponse struct {
+	Transactions []models.Transaction `json:"transactions"`
+}
+
+type StatementResponse struct {
+	models.Statement
+}
+
+type PortfolioResponse struct {
+	models.Portfolio
+}
+
+type HealthResponse struct {
+	Status string              `json:"status"`
+	Pairs  []models.PairHealth `json:"pairs"`
+}
+
+type CandlesResponse struct {
+	Ticker   string          `json:"ticker"`
+	Interval string          `json:"interval"`
+	Candles  []models.Candle `json:"candles"`
+}
+
+// UDF responses follow TradingView UDF datafeed protocol
+type UDFConfigResponse struct {
+	SupportsSearch         bool            `json:"supports_search"`
+	SupportsGroupRequest   bool            `json:"supports_group_request"`
+	SupportsMarks          bool            `json:"supports_marks"`
+	SupportsTimescaleMarks bool            `json:"supports_timescale_marks"`
+	SupportsTime           bool            `json:"supports_time"`
+	Exchanges              []UDFExchange   `json:"exchanges"`
+	SymbolsTypes           []UDFSymbolType `json:"symbols_types"`
+	SupportedResolutions   []string        `json:"supported_resolutions"`
+}
+
+type UDFExchange struct {
+	Value string `json:"value"`
+	Name  string `json:"name"`
+	Desc  string `json:"desc"`
+}
+
+type UDFSymbolType struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type UDFSymbolResponse struct {
+	Name                 string   `json:"name"`
+	Ticker               string   `json:"ticker"`
+	Description          string   `json:"description"`
+	Type                 string   `json:"type"`
+	Session              string   `json:"session"`
+	Exchange             string   `json:"exchange"`
+	ListedExchange       string   `json:"listed_exchange"`
+	Timezone             string   `json:"timezone"`
+	Minmov               int      `json:"minmov"`
+	Pricescale           int      `json:"pricescale"`
+	HasIntraday          bool     `json:"has_intraday"`
+	HasDaily             bool     `json:"has_daily"`
+	SupportedResolutions []string `json:"supported_resolutions"`
+	VolumePrecision      int      `json:"volume_precision"`
+	DataStatus           string   `json:"data_status"`
+}
+
+type UDFSearchResult struct {
+	Symbol      string `json:"symbol"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	Exchange    string `json:"exchange"`
+	Ticker      string `json:"ticker"`
+	Type        string `json:"type"`
+}
+
+type UDFHistoryResponse struct {
+	S        string    `json:"s"`
+	T        []int64   `json:"t,omitempty"`
+	O        []float64 `json:"o,omitempty"`
+	H        []float64 `json:"h,omitempty"`
+	L        []float64 `json:"l,omitempty"`
+	C        []float64 `json:"c,omitempty"`
+	V        []float64 `json:"v,omitempty"`
+	NextTime int64     `json:"nextTime,omitempty"`
+}
+
+type UDFMark struct {
+	Id             string `json:"id"`
+	Time           int64  `json:"time"`
+	Color          string `json:"color"`
+	Text           string `json:"text"`
+	Label          string `json:"label"`
+	LabelFontColor string `json:"labelFontColor"`
+	MinSize        int    `json:"minSize"`
+}
+
+type UDFErrorResponse struct {
+	S      string `json:"s"`
+	Errmsg string `json:"errmsg"`
 }
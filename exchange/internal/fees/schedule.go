@@ -0,0 +1,111 @@
+// Package fees implements maker/taker trading fee schedule with tiers by 30-day volume
+package fees
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"time"
+)
+
+// VolumeWindow is period of trading volume which selects fee tier
+const VolumeWindow = 30 * 24 * time.Hour
+
+var (
+	ErrNoTiers     = errors.New("fee tiers are empty")
+	ErrInvalidTier = errors.New("invalid fee tier")
+)
+
+type Tier struct {
+	MinVolume decimal.Decimal
+	Maker     decimal.Decimal
+	Taker     decimal.Decimal
+}
+
+type Schedule struct {
+	def   []Tier
+	pairs map[string][]Tier
+}
+
+func New(cfg config.FeeConfig) (*Schedule, error) {
+	def, err := newTiers(cfg.Tiers)
+	if err != nil {
+		return nil, fmt.Errorf("default tiers: %w", err)
+	}
+
+	pairs := make(map[string][]Tier, len(cfg.Pairs))
+	for ticker, tiersCfg := range cfg.Pairs {
+		tiers, err := newTiers(tiersCfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s tiers: %w", ticker, err)
+		}
+		pairs[ticker] = tiers
+	}
+
+	return &Schedule{def: def, pairs: pairs}, nil
+}
+
+func newTiers(cfg []config.FeeTierConfig) ([]Tier, error) {
+	if len(cfg) == 0 {
+		return nil, ErrNoTiers
+	}
+
+	tiers := make([]Tier, 0, len(cfg))
+	for _, t := range cfg {
+		if t.MinVolume < 0 || t.Maker < 0 || t.Taker < 0 || t.Maker >= 1 || t.Taker >= 1 {
+			return nil, ErrInvalidTier
+		}
+		tiers = append(tiers, Tier{
+			MinVolume: decimal.NewFromFloat(t.MinVolume),
+			Maker:     decimal.NewFromFloat(t.Maker),
+			Taker:     decimal.NewFromFloat(t.Taker),
+		})
+	}
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].MinVolume.LessThan(tiers[j].MinVolume)
+	})
+
+	return tiers, nil
+}
+
+// Tier returns the highest tier of ticker reached by 30-day volume,
+// volume below every tier still gets the first one
+func (s *Schedule) Tier(ticker string, volume decimal.Decimal) Tier {
+	tiers, ok := s.pairs[ticker]
+	if !ok {
+		tiers = s.def
+	}
+
+	tier := tiers[0]
+	for _, t := range tiers[1:] {
+		if volume.LessThan(t.MinVolume) {
+			break
+		}
+		tier = t
+	}
+	return tier
+}
+
+// Fee prices trade of notional, amount is rounded up to cents
+func (s *Schedule) Fee(ticker string,
+	volume decimal.Decimal,
+	kind models.FeeKind,
+	liquidity models.Liquidity,
+	notional decimal.Decimal) models.OrderFee {
+	tier := s.Tier(ticker, volume)
+	rate := tier.Taker
+	if liquidity == models.Maker {
+		rate = tier.Maker
+	}
+
+	return models.OrderFee{
+		Kind:      kind,
+		Liquidity: liquidity,
+		Notional:  notional.Round(2),
+		Rate:      rate,
+		Amount:    notional.Mul(rate).RoundUp(2),
+	}
+}
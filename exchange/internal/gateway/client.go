@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
+	"sync"
+	"time"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	maxMessageSize = 4096
+)
+
+const (
+	opSubscribe   = "subscribe"
+	opUnsubscribe = "unsubscribe"
+	opPing        = "ping"
+)
+
+const (
+	typePrice        = "price"
+	typeEvent        = "event"
+	typeSubscribed   = "subscribed"
+	typeUnsubscribed = "unsubscribed"
+	typePong         = "pong"
+	typeError        = "error"
+)
+
+// request is message sent by client: {"op":"subscribe","tickers":["BTC/USDT"]}
+type request struct {
+	Op      string   `json:"op"`
+	Tickers []string `json:"tickers"`
+}
+
+// message is message sent to client, Event is envelope of domain event as published
+type message struct {
+	Type    string          `json:"type"`
+	Ticker  string          `json:"ticker,omitempty"`
+	Price   string          `json:"price,omitempty"`
+	Tickers []string        `json:"tickers,omitempty"`
+	Event   json.RawMessage `json:"event,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// Client is WebSocket connection, userId is zero for anonymous client receiving only prices
+type Client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	userId int64
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+	// tickers is guarded by hub.mu
+	tickers map[string]struct{}
+}
+
+// Serve registers connection in hub and pumps messages until it is closed
+func (h *Hub) Serve(conn *websocket.Conn, userId int64) {
+	c := &Client{
+		hub:     h,
+		conn:    conn,
+		userId:  userId,
+		send:    make(chan []byte, h.cfg.SendBuffer),
+		done:    make(chan struct{}),
+		tickers: make(map[string]struct{}),
+	}
+	h.register(c)
+	h.log.Debug("ws client connected", "user_id", userId)
+
+	go c.writePump()
+	c.readPump()
+}
+
+// deliver queues payload without blocking, client with full buffer is disconnected
+func (c *Client) deliver(payload []byte) {
+	select {
+	case c.send <- payload:
+	case <-c.done:
+	default:
+		c.hub.log.Warn("ws client is too slow, disconnecting", "user_id", c.userId)
+		c.close()
+	}
+}
+
+func (c *Client) reply(msg message) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	c.deliver(payload)
+}
+
+func (c *Client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.close()
+		c.hub.log.Debug("ws client disconnected", "user_id", c.userId)
+	}()
+
+	pongWait := 2 * c.hub.cfg.PingInterval
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var req request
+		if err := c.conn.ReadJSON(&req); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.reply(message{Type: typeError, Error: "invalid message"})
+				continue
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		tickers := make([]string, 0, len(req.Tickers))
+		for _, ticker := range req.Tickers {
+			if ticker = normalizeTicker(ticker); ticker != "" {
+				tickers = append(tickers, ticker)
+			}
+		}
+
+		switch req.Op {
+		case opSubscribe:
+			if err := c.hub.subscribe(c, tickers); err != nil {
+				c.reply(message{Type: typeError, Error: err.Error()})
+				continue
+			}
+			c.reply(message{Type: typeSubscribed, Tickers: tickers})
+		case opUnsubscribe:
+			c.hub.unsubscribe(c, tickers)
+			c.reply(message{Type: typeUnsubscribed, Tickers: tickers})
+		case opPing:
+			c.reply(message{Type: typePong})
+		default:
+			c.reply(message{Type: typeError, Error: "unknown op"})
+		}
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(c.hub.cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+
+	for {
+		select {
+		case payload := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
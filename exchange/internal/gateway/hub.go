@@ -0,0 +1,186 @@
+// Package gateway fans out JetStream price ticks and user events to WebSocket clients
+package gateway
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/events"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const pricesSubj = "prices."
+
+var ErrTooManySubscriptions = errors.New("too many subscriptions")
+
+// Hub keeps clients by subscribed ticker and by user. Delivery never blocks:
+// client which can't keep up is disconnected
+type Hub struct {
+	log *slog.Logger
+	cfg config.GatewayConfig
+
+	mu      sync.RWMutex
+	tickers map[string]map[*Client]struct{}
+	users   map[int64]map[*Client]struct{}
+}
+
+func NewHub(log *slog.Logger, cfg config.GatewayConfig) *Hub {
+	return &Hub{
+		log:     log,
+		cfg:     cfg,
+		tickers: make(map[string]map[*Client]struct{}),
+		users:   make(map[int64]map[*Client]struct{}),
+	}
+}
+
+// Consume subscribes hub to new messages of price and event streams
+func (h *Hub) Consume(js nats.JetStreamContext) ([]*nats.Subscription, error) {
+	const op = "gateway.Hub.Consume"
+
+	subs := make([]*nats.Subscription, 0, len(events.Subjects)+1)
+	sub, err := js.Subscribe(pricesSubj+"*", h.handlePrice, nats.DeliverNew(), nats.AckNone())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	subs = append(subs, sub)
+
+	for _, subject := range events.Subjects {
+		sub, err := js.Subscribe(subject, h.handleEvent, nats.DeliverNew(), nats.AckNone())
+		if err != nil {
+			for _, s := range subs {
+				s.Unsubscribe()
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, nil
+}
+
+func (h *Hub) handlePrice(msg *nats.Msg) {
+	ticker := tickerFromSubject(msg.Subject)
+	payload, err := json.Marshal(message{Type: typePrice, Ticker: ticker, Price: string(msg.Data)})
+	if err != nil {
+		h.log.Error("marshal price failed", "ticker", ticker, "error", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.tickers[ticker] {
+		c.deliver(payload)
+	}
+}
+
+func (h *Hub) handleEvent(msg *nats.Msg) {
+	var event events.Event
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		h.log.Error("invalid event", "subject", msg.Subject, "error", err)
+		return
+	}
+	payload, err := json.Marshal(message{Type: typeEvent, Event: msg.Data})
+	if err != nil {
+		h.log.Error("marshal event failed", "subject", msg.Subject, "error", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.users[event.UserId] {
+		c.deliver(payload)
+	}
+}
+
+func (h *Hub) register(c *Client) {
+	if c.userId == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.users[c.userId] == nil {
+		h.users[c.userId] = make(map[*Client]struct{})
+	}
+	h.users[c.userId][c] = struct{}{}
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ticker := range c.tickers {
+		h.removeTicker(c, ticker)
+	}
+	if clients, ok := h.users[c.userId]; ok {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(h.users, c.userId)
+		}
+	}
+}
+
+func (h *Hub) subscribe(c *Client, tickers []string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	added := 0
+	for _, ticker := range tickers {
+		if _, ok := c.tickers[ticker]; !ok {
+			added++
+		}
+	}
+	if len(c.tickers)+added > h.cfg.MaxSubscriptions {
+		return ErrTooManySubscriptions
+	}
+
+	for _, ticker := range tickers {
+		c.tickers[ticker] = struct{}{}
+		if h.tickers[ticker] == nil {
+			h.tickers[ticker] = make(map[*Client]struct{})
+		}
+		h.tickers[ticker][c] = struct{}{}
+	}
+	return nil
+}
+
+func (h *Hub) unsubscribe(c *Client, tickers []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ticker := range tickers {
+		h.removeTicker(c, ticker)
+	}
+}
+
+// removeTicker must be called with mu locked
+func (h *Hub) removeTicker(c *Client, ticker string) {
+	delete(c.tickers, ticker)
+	if clients, ok := h.tickers[ticker]; ok {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(h.tickers, ticker)
+		}
+	}
+}
+
+// tickerFromSubject converts prices.BTCUSDT to BTC/USDT
+func tickerFromSubject(subject string) string {
+	const quoteAsset = "USDT"
+	key := strings.TrimPrefix(subject, pricesSubj)
+	if !strings.HasSuffix(key, quoteAsset) {
+		return key
+	}
+	return strings.TrimSuffix(key, quoteAsset) + "/" + quoteAsset
+}
+
+// normalizeTicker accepts btc/usdt and BTCUSDT as BTC/USDT
+func normalizeTicker(ticker string) string {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if strings.Contains(ticker, "/") {
+		return ticker
+	}
+	return tickerFromSubject(pricesSubj + ticker)
+}
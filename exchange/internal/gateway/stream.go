@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/events"
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Update is one message of Stream. Id is JetStream sequence of user event
+// in events stream, price updates have no id
+type Update struct {
+	Id    uint64
+	Event string
+	Data  []byte
+}
+
+// Streamer opens per-connection streams of prices and user events for SSE.
+// Each stream has its own ordered consumers, so reconnecting client resumes right
+// after last event it has seen and slow client holds back only its own consumers.
+// Events consumer reads only subjects of stream's user
+type Streamer struct {
+	log *slog.Logger
+	js  nats.JetStreamContext
+	cfg config.GatewayConfig
+}
+
+func NewStreamer(log *slog.Logger, js nats.JetStreamContext, cfg config.GatewayConfig) *Streamer {
+	return &Streamer{log: log, js: js, cfg: cfg}
+}
+
+type Stream struct {
+	updates      chan Update
+	done         chan struct{}
+	eventTimeout time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	subs   []*nats.Subscription
+}
+
+// Updates channel is never closed, stop reading once stream is closed
+func (s *Stream) Updates() <-chan Update {
+	return s.updates
+}
+
+// Done is closed when stream is closed, also by stream itself when client is too slow
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+	for _, sub := range s.subs {
+		sub.Unsubscribe()
+	}
+}
+
+func (s *Stream) addSub(sub *nats.Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		sub.Unsubscribe()
+		return
+	}
+	s.subs = append(s.subs, sub)
+}
+
+// pushPrice drops tick when client is behind, next tick supersedes it anyway
+func (s *Stream) pushPrice(u Update) {
+	select {
+	case s.updates <- u:
+	default:
+	}
+}
+
+// pushEvent waits for client up to event timeout, holding back events consumer of this stream only.
+// Stream of client which doesn't take event in time is closed, on reconnect client resumes
+// from its last event
+func (s *Stream) pushEvent(u Update) bool {
+	timer := time.NewTimer(s.eventTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.updates <- u:
+		return true
+	case <-s.done:
+		return true
+	case <-timer.C:
+		s.Close()
+		return false
+	}
+}
+
+// Open streams new prices of tickers and, for authenticated user, events of user
+// published after lastEventId (zero - only new events)
+func (st *Streamer) Open(userId int64, tickers []string, lastEventId uint64) (*Stream, error) {
+	const op = "gateway.Streamer.Open"
+
+	if len(tickers) > st.cfg.MaxSubscriptions {
+		return nil, ErrTooManySubscriptions
+	}
+
+	s := &Stream{
+		updates:      make(chan Update, st.cfg.SendBuffer),
+		done:         make(chan struct{}),
+		eventTimeout: st.cfg.EventTimeout,
+	}
+
+	for _, ticker := range tickers {
+		if ticker = normalizeTicker(ticker); ticker == "" {
+			continue
+		}
+		subject := pricesSubj + strings.ReplaceAll(ticker, "/", "")
+		sub, err := st.js.Subscribe(subject, func(msg *nats.Msg) {
+			data, err := json.Marshal(message{Type: typePrice, Ticker: ticker, Price: string(msg.Data)})
+			if err != nil {
+				return
+			}
+			s.pushPrice(Update{Event: typePrice, Data: data})
+		}, nats.OrderedConsumer(), nats.DeliverNew())
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		s.addSub(sub)
+	}
+
+	if userId == 0 {
+		return s, nil
+	}
+
+	start := nats.DeliverNew()
+	if lastEventId > 0 {
+		start = nats.StartSequence(lastEventId + 1)
+	}
+	sub, err := st.js.Subscribe(events.UserSubject(userId), func(msg *nats.Msg) {
+		var event events.Event
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			st.log.Error("invalid event", "subject", msg.Subject, "error", err)
+			return
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			st.log.Error("event metadata", "subject", msg.Subject, "error", err)
+			return
+		}
+		if !s.pushEvent(Update{Id: meta.Sequence.Stream, Event: string(event.Type), Data: msg.Data}) {
+			st.log.Warn("slow SSE client disconnected", "userId", userId, "eventId", meta.Sequence.Stream)
+		}
+	}, nats.BindStream(events.StreamName), nats.OrderedConsumer(), start)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	s.addSub(sub)
+
+	return s, nil
+}
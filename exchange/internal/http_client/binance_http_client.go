@@ -3,67 +3,113 @@ package http_client
 import (
 	"Exchange/internal/config"
 	"Exchange/internal/domain/models"
-	"encoding/json"
 	"fmt"
-	"io"
+	"github.com/shopspring/decimal"
 	"log/slog"
 	"net/http"
 	"time"
 )
 
 type BinanceHTTPClient struct {
-	baseURL  string
-	endpoint string
-	streams  []string
-	log      slog.Logger
-	client   *http.Client
+	baseURL        string
+	endpoint       string
+	klinesEndpoint string
+	streams        []string
+	log            slog.Logger
+	client         *http.Client
 }
 
 func New(cfg config.Config, log slog.Logger) *BinanceHTTPClient {
 	return &BinanceHTTPClient{
-		baseURL:  cfg.BinanceConfig.BaseURL,
-		endpoint: cfg.BinanceConfig.Endpoint,
-		streams:  cfg.BinanceConfig.Streams,
-		log:      log,
-		client:   &http.Client{Timeout: 10 * time.Second},
+		baseURL:        cfg.BinanceConfig.BaseURL,
+		endpoint:       cfg.BinanceConfig.Endpoint,
+		klinesEndpoint: cfg.BinanceConfig.KlinesEndpoint,
+		streams:        cfg.BinanceConfig.Streams,
+		log:            log,
+		client:         &http.Client{Timeout: 10 * time.Second},
 	}
 }
 
+func (pr *BinanceHTTPClient) Name() string {
+	return "binance"
+}
+
 func (pr *BinanceHTTPClient) GetPrice() ([]models.PriceResponse, error) {
-	log := pr.log.With("method", "GetPrice")
+	log := pr.log.With("method", "GetPrice", "source", pr.Name())
 
 	reqUrl := fmt.Sprintf("%s%s%s", pr.baseURL, pr.endpoint, pr.addParamsToUrl())
 
-	// log.Debug("making request to Binance API", "url", reqUrl)
-	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
-	if err != nil {
-		log.Error("failed to create request", "error", err)
-		return nil, fmt.Errorf("could not create request: %w", err)
+	priceResp := []models.PriceResponse{}
+	if err := getJSON(pr.client, log, reqUrl, &priceResp); err != nil {
+		return nil, err
 	}
+	// ticker price has no time, prices are quoted at response
+	now := time.Now()
+	for i := range priceResp {
+		priceResp[i].Time = now
+	}
+
+	return priceResp, nil
+}
+
+// GetKlines returns up to limit bars of symbol (BTCUSDT) opened since start, oldest first.
+// Bars carry no ticker, interval is Binance one (1m, 5m, 15m, 1h, 1d)
+func (pr *BinanceHTTPClient) GetKlines(symbol, interval string, start time.Time, limit int) ([]models.Candle, error) {
+	log := pr.log.With("method", "GetKlines", "source", pr.Name(), "symbol", symbol, "interval", interval)
+
+	reqUrl := fmt.Sprintf("%s%s?symbol=%s&interval=%s&startTime=%d&limit=%d",
+		pr.baseURL, pr.klinesEndpoint, symbol, interval, start.UnixMilli(), limit)
 
-	resp, err := pr.client.Do(req)
-	if err != nil {
-		log.Error("failed to make request", "error", err)
-		return nil, fmt.Errorf("could not make request: %w", err)
+	// kline is [open time, open, high, low, close, volume, close time, ...]
+	var klines [][]any
+	if err := getJSON(pr.client, log, reqUrl, &klines); err != nil {
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Error("unexpected status code",
-			"status", resp.StatusCode,
-			"response", string(body))
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	candles := make([]models.Candle, 0, len(klines))
+	for _, kline := range klines {
+		candle, err := candleOf(kline)
+		if err != nil {
+			log.Error("invalid kline", "kline", kline, "error", err)
+			return nil, err
+		}
+		candle.Interval = interval
+		candles = append(candles, candle)
 	}
 
-	priceResp := []models.PriceResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(&priceResp); err != nil {
-		log.Error("failed to decode response", "error", err)
-		return nil, fmt.Errorf("decode response: %w", err)
+	return candles, nil
+}
+
+func candleOf(kline []any) (models.Candle, error) {
+	if len(kline) < 6 {
+		return models.Candle{}, fmt.Errorf("kline has %d fields", len(kline))
+	}
+	openTime, ok := kline[0].(float64)
+	if !ok {
+		return models.Candle{}, fmt.Errorf("invalid open time %v", kline[0])
 	}
 
-	// log.Debug("successfully received price")
+	var values [5]decimal.Decimal
+	for i := range values {
+		raw, ok := kline[i+1].(string)
+		if !ok {
+			return models.Candle{}, fmt.Errorf("invalid value %v", kline[i+1])
+		}
+		value, err := decimal.NewFromString(raw)
+		if err != nil {
+			return models.Candle{}, err
+		}
+		values[i] = value
+	}
 
-	return priceResp, nil
+	return models.Candle{
+		OpenTime: time.UnixMilli(int64(openTime)).UTC(),
+		Open:     values[0],
+		High:     values[1],
+		Low:      values[2],
+		Close:    values[3],
+		Volume:   values[4],
+	}, nil
 }
 
 func (pr *BinanceHTTPClient) addParamsToUrl() string {
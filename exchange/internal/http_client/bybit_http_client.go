@@ -0,0 +1,67 @@
+package http_client
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// BybitHTTPClient gets spot last prices from Bybit v5 market tickers
+type BybitHTTPClient struct {
+	baseURL  string
+	endpoint string
+	symbols  map[string]struct{}
+	log      slog.Logger
+	client   *http.Client
+}
+
+type bybitTickersResponse struct {
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
+	// Time is unix milliseconds of response
+	Time   int64 `json:"time"`
+	Result struct {
+		List []struct {
+			Symbol    string `json:"symbol"`
+			LastPrice string `json:"lastPrice"`
+		} `json:"list"`
+	} `json:"result"`
+}
+
+func NewBybit(cfg config.Config, log slog.Logger) *BybitHTTPClient {
+	return &BybitHTTPClient{
+		baseURL:  cfg.BybitConfig.BaseURL,
+		endpoint: cfg.BybitConfig.Endpoint,
+		symbols:  symbolSet(cfg.BinanceConfig.Streams),
+		log:      log,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *BybitHTTPClient) Name() string {
+	return "bybit"
+}
+
+func (c *BybitHTTPClient) GetPrice() ([]models.PriceResponse, error) {
+	log := c.log.With("method", "GetPrice", "source", c.Name())
+
+	var resp bybitTickersResponse
+	if err := getJSON(c.client, log, c.baseURL+c.endpoint+"?category=spot", &resp); err != nil {
+		return nil, err
+	}
+	if resp.RetCode != 0 {
+		log.Error("bybit returned error", "code", resp.RetCode, "message", resp.RetMsg)
+		return nil, fmt.Errorf("bybit error %d: %s", resp.RetCode, resp.RetMsg)
+	}
+
+	prices := make([]models.PriceResponse, 0, len(c.symbols))
+	for _, t := range resp.Result.List {
+		if _, ok := c.symbols[t.Symbol]; ok {
+			prices = append(prices, models.PriceResponse{Symbol: t.Symbol, Price: t.LastPrice, Time: time.UnixMilli(resp.Time)})
+		}
+	}
+	return prices, nil
+}
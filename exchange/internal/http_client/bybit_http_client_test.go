@@ -0,0 +1,81 @@
+package http_client
+
+import (
+	"Exchange/internal/domain/models"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBybitGetPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture fixture
+		want    []models.PriceResponse
+		wantErr bool
+	}{
+		{
+			name: "tickers",
+			fixture: fixture{status: http.StatusOK, body: `{"retCode":0,"retMsg":"OK","time":1733486400000,"result":{"category":"spot","list":[
+				{"symbol":"BTCUSDT","lastPrice":"62000.01"},
+				{"symbol":"ETHUSDT","lastPrice":"3500.5"},
+				{"symbol":"DOGEUSDT","lastPrice":"0.4"}]}}`},
+			want: []models.PriceResponse{
+				{Symbol: "BTCUSDT", Price: "62000.01", Time: time.UnixMilli(1733486400000)},
+				{Symbol: "ETHUSDT", Price: "3500.5", Time: time.UnixMilli(1733486400000)},
+			},
+		},
+		{
+			name:    "no configured symbols",
+			fixture: fixture{status: http.StatusOK, body: `{"retCode":0,"time":1,"result":{"list":[{"symbol":"DOGEUSDT","lastPrice":"0.4"}]}}`},
+			want:    []models.PriceResponse{},
+		},
+		{
+			name:    "error code",
+			fixture: fixture{status: http.StatusOK, body: `{"retCode":10001,"retMsg":"params error","result":{}}`},
+			wantErr: true,
+		},
+		{
+			name:    "error status",
+			fixture: fixture{status: http.StatusForbidden, body: `{"retCode":10006,"retMsg":"Too many visits!"}`},
+			wantErr: true,
+		},
+		{
+			name:    "malformed body",
+			fixture: fixture{status: http.StatusOK, body: `{"retCode":0,"result":{"list":[`},
+			wantErr: true,
+		},
+		{
+			name:    "html body",
+			fixture: fixture{status: http.StatusOK, body: `<html>maintenance</html>`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			cfg := testConfig()
+			cfg.BybitConfig.BaseURL = serveFixture(t, tt.fixture, &query)
+			cfg.BybitConfig.Endpoint = "/v5/market/tickers"
+
+			got, err := NewBybit(cfg, testLogger()).GetPrice()
+			if query != "category=spot" {
+				t.Errorf("got query %q, want category=spot", query)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package http_client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// getJSON requests url and decodes response body to dst
+func getJSON(client *http.Client, log *slog.Logger, url string, dst any) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		log.Error("failed to create request", "error", err)
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Error("failed to make request", "error", err)
+		return fmt.Errorf("could not make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Error("unexpected status code",
+			"status", resp.StatusCode,
+			"response", string(body))
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		log.Error("failed to decode response", "error", err)
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// symbolSet is set of configured symbols, exchanges return tickers of all symbols
+func symbolSet(symbols []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(symbols))
+	for _, symbol := range symbols {
+		set[strings.ToUpper(symbol)] = struct{}{}
+	}
+	return set
+}
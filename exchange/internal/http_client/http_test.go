@@ -0,0 +1,38 @@
+package http_client
+
+import (
+	"Exchange/internal/config"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fixture is exchange answer served by local stub
+type fixture struct {
+	status int
+	body   string
+}
+
+// serveFixture starts stub answering every request with f and returns its url,
+// query of the last request is written to query
+func serveFixture(t *testing.T, f fixture, query *string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(f.status)
+		io.WriteString(w, f.body)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func testConfig() config.Config {
+	return config.Config{BinanceConfig: config.BinanceConfig{Streams: []string{"btcusdt", "ETHUSDT"}}}
+}
+
+func testLogger() slog.Logger {
+	return *slog.New(slog.NewTextHandler(io.Discard, nil))
+}
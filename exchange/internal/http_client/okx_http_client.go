@@ -0,0 +1,75 @@
+package http_client
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OKXHTTPClient gets spot last prices from OKX v5 market tickers
+type OKXHTTPClient struct {
+	baseURL  string
+	endpoint string
+	symbols  map[string]struct{}
+	log      slog.Logger
+	client   *http.Client
+}
+
+type okxTickersResponse struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data []struct {
+		// InstId is BTC-USDT
+		InstId string `json:"instId"`
+		Last   string `json:"last"`
+		// Ts is unix milliseconds
+		Ts string `json:"ts"`
+	} `json:"data"`
+}
+
+func NewOKX(cfg config.Config, log slog.Logger) *OKXHTTPClient {
+	return &OKXHTTPClient{
+		baseURL:  cfg.OKXConfig.BaseURL,
+		endpoint: cfg.OKXConfig.Endpoint,
+		symbols:  symbolSet(cfg.BinanceConfig.Streams),
+		log:      log,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *OKXHTTPClient) Name() string {
+	return "okx"
+}
+
+func (c *OKXHTTPClient) GetPrice() ([]models.PriceResponse, error) {
+	log := c.log.With("method", "GetPrice", "source", c.Name())
+
+	var resp okxTickersResponse
+	if err := getJSON(c.client, log, c.baseURL+c.endpoint+"?instType=SPOT", &resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != "0" {
+		log.Error("okx returned error", "code", resp.Code, "message", resp.Msg)
+		return nil, fmt.Errorf("okx error %s: %s", resp.Code, resp.Msg)
+	}
+
+	prices := make([]models.PriceResponse, 0, len(c.symbols))
+	for _, t := range resp.Data {
+		symbol := strings.ReplaceAll(t.InstId, "-", "")
+		if _, ok := c.symbols[symbol]; !ok {
+			continue
+		}
+		ts, err := strconv.ParseInt(t.Ts, 10, 64)
+		if err != nil {
+			log.Warn("invalid quote time", "symbol", symbol, "ts", t.Ts)
+			continue
+		}
+		prices = append(prices, models.PriceResponse{Symbol: symbol, Price: t.Last, Time: time.UnixMilli(ts)})
+	}
+	return prices, nil
+}
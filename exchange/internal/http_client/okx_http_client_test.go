@@ -0,0 +1,85 @@
+package http_client
+
+import (
+	"Exchange/internal/domain/models"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOKXGetPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture fixture
+		want    []models.PriceResponse
+		wantErr bool
+	}{
+		{
+			name: "tickers",
+			fixture: fixture{status: http.StatusOK, body: `{"code":"0","msg":"","data":[
+				{"instId":"BTC-USDT","last":"62000.01","ts":"1733486400000"},
+				{"instId":"ETH-USDT","last":"3500.5","ts":"1733486400500"},
+				{"instId":"DOGE-USDT","last":"0.4","ts":"1733486400000"}]}`},
+			want: []models.PriceResponse{
+				{Symbol: "BTCUSDT", Price: "62000.01", Time: time.UnixMilli(1733486400000)},
+				{Symbol: "ETHUSDT", Price: "3500.5", Time: time.UnixMilli(1733486400500)},
+			},
+		},
+		{
+			name: "invalid quote time is skipped",
+			fixture: fixture{status: http.StatusOK, body: `{"code":"0","data":[
+				{"instId":"BTC-USDT","last":"62000.01","ts":"now"},
+				{"instId":"ETH-USDT","last":"3500.5","ts":"1733486400500"}]}`},
+			want: []models.PriceResponse{
+				{Symbol: "ETHUSDT", Price: "3500.5", Time: time.UnixMilli(1733486400500)},
+			},
+		},
+		{
+			name:    "error code",
+			fixture: fixture{status: http.StatusOK, body: `{"code":"50011","msg":"Rate limit reached","data":[]}`},
+			wantErr: true,
+		},
+		{
+			name:    "error status",
+			fixture: fixture{status: http.StatusServiceUnavailable, body: `{"code":"50001","msg":"Service temporarily unavailable"}`},
+			wantErr: true,
+		},
+		{
+			name:    "malformed body",
+			fixture: fixture{status: http.StatusOK, body: `{"code":"0","data":[{"instId":`},
+			wantErr: true,
+		},
+		{
+			name:    "wrong type",
+			fixture: fixture{status: http.StatusOK, body: `{"code":0,"data":{}}`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			cfg := testConfig()
+			cfg.OKXConfig.BaseURL = serveFixture(t, tt.fixture, &query)
+			cfg.OKXConfig.Endpoint = "/api/v5/market/tickers"
+
+			got, err := NewOKX(cfg, testLogger()).GetPrice()
+			if query != "instType=SPOT" {
+				t.Errorf("got query %q, want instType=SPOT", query)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
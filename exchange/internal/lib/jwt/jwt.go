@@ -0,0 +1,75 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"strconv"
+	"time"
+)
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	UserID    int64  `json:"uid"`
+	Email     string `json:"email"`
+	TokenType string `json:"typ"`
+	// IssuedAtMs is issue time in unix milliseconds, iat has whole seconds only
+	IssuedAtMs int64 `json:"iat_ms"`
+	jwt.RegisteredClaims
+}
+
+// IssuedAtTime returns issue time with millisecond precision
+func (c Claims) IssuedAtTime() time.Time {
+	return time.UnixMilli(c.IssuedAtMs)
+}
+
+// NewToken signs token of given type for user and returns it with its id (jti)
+func NewToken(userId int64, email, tokenType string, ttl time.Duration, secret string) (string, Claims, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:     userId,
+		Email:      email,
+		TokenType:  tokenType,
+		IssuedAtMs: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   strconv.FormatInt(userId, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		return "", Claims{}, fmt.Errorf("sign token: %w", err)
+	}
+
+	return token, claims, nil
+}
+
+// ParseToken validates signature, expiration and type of token
+func ParseToken(tokenStr, tokenType, secret string) (Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if claims.TokenType != tokenType {
+		return Claims{}, fmt.Errorf("%w: unexpected token type %q", ErrInvalidToken, claims.TokenType)
+	}
+	if claims.IssuedAtMs <= 0 {
+		return Claims{}, fmt.Errorf("%w: no issue time", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,55 @@
+package jwt
+
+import (
+	"errors"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"testing"
+	"time"
+)
+
+func TestTokenKeepsIssueTimeInMilliseconds(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+	token, issued, err := NewToken(1, "user@example.com", AccessToken, time.Minute, "secret")
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	claims, err := ParseToken(token, AccessToken, "secret")
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if got := claims.IssuedAtTime(); !got.Equal(issued.IssuedAtTime()) || got.Before(before) {
+		t.Errorf("got issue time %v, want %v", got, issued.IssuedAtTime())
+	}
+	if claims.IssuedAtTime().UnixMilli() != issued.IssuedAtMs {
+		t.Errorf("got %d ms, want %d", claims.IssuedAtTime().UnixMilli(), issued.IssuedAtMs)
+	}
+}
+
+func TestParseTokenRejectsTokenWithoutIssueTime(t *testing.T) {
+	claims := Claims{UserID: 1, TokenType: AccessToken, RegisteredClaims: jwtlib.RegisteredClaims{
+		ExpiresAt: jwtlib.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+	token, err := jwtlib.NewWithClaims(jwtlib.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	if _, err := ParseToken(token, AccessToken, "secret"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestParseTokenRejectsOtherType(t *testing.T) {
+	token, _, err := NewToken(1, "user@example.com", RefreshToken, time.Minute, "secret")
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	if _, err := ParseToken(token, AccessToken, "secret"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("got %v, want ErrInvalidToken", err)
+	}
+	if _, err := ParseToken(token, RefreshToken, "other-secret"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("got %v for wrong secret, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package marketdata guards trading against prices which stopped updating
+package marketdata
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrMarketDataUnavailable = errors.New("market data unavailable")
+
+type quoteReader interface {
+	GetQuote(ctx context.Context, ticker string) (models.Quote, error)
+}
+
+// Guard decides whether price of pair is fresh enough to trade at
+type Guard struct {
+	cfg config.MarketDataConfig
+}
+
+func NewGuard(cfg config.MarketDataConfig) *Guard {
+	return &Guard{cfg: cfg}
+}
+
+// MaxAge returns max price age of ticker (BTC/USDT)
+func (g *Guard) MaxAge(ticker string) time.Duration {
+	if age, ok := g.cfg.PairMaxPriceAge[ticker]; ok {
+		return age
+	}
+	return g.cfg.MaxPriceAge
+}
+
+// Check returns ErrMarketDataUnavailable when quote of ticker is older than its max age.
+// Quote without time can't be shown to be fresh, so it is stale too
+func (g *Guard) Check(ticker string, quote models.Quote, now time.Time) error {
+	if quote.Time.IsZero() {
+		return fmt.Errorf("%w: %s price has no quote time", ErrMarketDataUnavailable, ticker)
+	}
+	if age := now.Sub(quote.Time); age > g.MaxAge(ticker) {
+		return fmt.Errorf("%w: %s price is %s old", ErrMarketDataUnavailable, ticker, age.Truncate(time.Millisecond))
+	}
+	return nil
+}
+
+// Monitor reports freshness of last and mark prices of traded pairs
+type Monitor struct {
+	guard   *Guard
+	tickers []string
+	last    quoteReader
+	mark    quoteReader
+}
+
+// NewMonitor watches symbols (BTCUSDT) quoted in USDT
+func NewMonitor(guard *Guard, symbols []string, last, mark quoteReader) *Monitor {
+	tickers := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		tickers = append(tickers, strings.TrimSuffix(symbol, "USDT")+"/USDT")
+	}
+	return &Monitor{guard: guard, tickers: tickers, last: last, mark: mark}
+}
+
+// Health returns freshness of every pair, pair without price is stale
+func (m *Monitor) Health(ctx context.Context) []models.PairHealth {
+	now := time.Now()
+	pairs := make([]models.PairHealth, 0, len(m.tickers))
+	for _, ticker := range m.tickers {
+		lastTime, lastFresh := m.check(ctx, m.last, ticker, now)
+		markTime, markFresh := m.check(ctx, m.mark, ticker, now)
+		pairs = append(pairs, models.PairHealth{
+			Ticker:        ticker,
+			LastPriceTime: lastTime,
+			MarkPriceTime: markTime,
+			MaxAgeSeconds: m.guard.MaxAge(ticker).Seconds(),
+			Stale:         !lastFresh || !markFresh,
+		})
+	}
+	return pairs
+}
+
+// check returns quote time of ticker, if it is known, and whether quote is fresh
+func (m *Monitor) check(ctx context.Context, reader quoteReader, ticker string, now time.Time) (*time.Time, bool) {
+	quote, err := reader.GetQuote(ctx, ticker)
+	if err != nil {
+		return nil, false
+	}
+	var quoteTime *time.Time
+	if !quote.Time.IsZero() {
+		quoteTime = &quote.Time
+	}
+	return quoteTime, m.guard.Check(ticker, quote, now) == nil
+}
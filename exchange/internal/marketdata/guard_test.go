@@ -0,0 +1,39 @@
+package marketdata
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGuardCheck(t *testing.T) {
+	now := time.Now()
+	guard := NewGuard(config.MarketDataConfig{
+		MaxPriceAge:     15 * time.Second,
+		PairMaxPriceAge: map[string]time.Duration{"BTC/USDT": 5 * time.Second},
+	})
+
+	tests := []struct {
+		name      string
+		ticker    string
+		quoteTime time.Time
+		wantStale bool
+	}{
+		{name: "fresh", ticker: "BTC/USDT", quoteTime: now.Add(-time.Second)},
+		{name: "older than pair max age", ticker: "BTC/USDT", quoteTime: now.Add(-6 * time.Second), wantStale: true},
+		{name: "within default max age", ticker: "ETH/USDT", quoteTime: now.Add(-6 * time.Second)},
+		{name: "older than default max age", ticker: "ETH/USDT", quoteTime: now.Add(-16 * time.Second), wantStale: true},
+		{name: "without time", ticker: "TEST/USDT", wantStale: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := guard.Check(tt.ticker, models.Quote{Price: "1", Time: tt.quoteTime}, now)
+			if stale := errors.Is(err, ErrMarketDataUnavailable); stale != tt.wantStale {
+				t.Fatalf("got error %v, want stale %v", err, tt.wantStale)
+			}
+		})
+	}
+}
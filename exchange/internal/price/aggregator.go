@@ -0,0 +1,175 @@
+// Package price combines prices of several exchanges into index price
+// and derives mark price from index and last price
+package price
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrNoSources = errors.New("no price source is available")
+
+// Source is exchange REST client returning last prices of configured symbols
+type Source interface {
+	Name() string
+	GetPrice() ([]models.PriceResponse, error)
+}
+
+// Aggregator computes index price of each symbol as median across sources
+// which answered this round. Outliers are rejected before taking median,
+// so single broken or manipulated source can't move the index
+type Aggregator struct {
+	log     slog.Logger
+	cfg     config.IndexConfig
+	sources []Source
+}
+
+func NewAggregator(log slog.Logger, cfg config.IndexConfig, sources ...Source) *Aggregator {
+	return &Aggregator{
+		log:     log,
+		cfg:     cfg,
+		sources: sources,
+	}
+}
+
+func (a *Aggregator) Name() string {
+	return "index"
+}
+
+// Run passes index prices to onPrices every interval until ctx is done
+func (a *Aggregator) Run(ctx context.Context, onPrices func([]models.PriceResponse)) {
+	const op = "price.Run"
+	log := a.log.With("op", op)
+	if a.cfg.Interval <= 0 {
+		log.Error("index interval must be positive", "interval", a.cfg.Interval)
+		return
+	}
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		prices, err := a.GetPrice()
+		if err != nil {
+			log.Error("index price failed", "error", err)
+		} else {
+			onPrices(prices)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// quote is price of one source
+type quote struct {
+	price decimal.Decimal
+	time  time.Time
+}
+
+// GetPrice polls all sources concurrently and returns index price of every symbol
+// quoted by at least MinSources sources after outlier rejection.
+// Index is as old as the oldest quote it is made of
+func (a *Aggregator) GetPrice() ([]models.PriceResponse, error) {
+	log := a.log.With("method", "GetPrice")
+
+	quotes := make(map[string][]quote)
+	healthy := 0
+	for name, prices := range a.poll() {
+		healthy++
+		for _, p := range prices {
+			price, err := decimal.NewFromString(p.Price)
+			if err != nil || !price.IsPositive() {
+				log.Warn("invalid quote", "source", name, "symbol", p.Symbol, "price", p.Price)
+				continue
+			}
+			quotes[p.Symbol] = append(quotes[p.Symbol], quote{price: price, time: p.Time})
+		}
+	}
+	if healthy == 0 {
+		return nil, ErrNoSources
+	}
+
+	maxDeviation := decimal.NewFromFloat(a.cfg.MaxDeviation)
+	index := make([]models.PriceResponse, 0, len(quotes))
+	for symbol, symbolQuotes := range quotes {
+		accepted := rejectOutliers(symbolQuotes, maxDeviation)
+		if len(accepted) < a.cfg.MinSources {
+			log.Warn("not enough agreeing sources", "symbol", symbol, "quotes", len(symbolQuotes), "accepted", len(accepted))
+			continue
+		}
+
+		prices := make([]decimal.Decimal, 0, len(accepted))
+		oldest := accepted[0].time
+		for _, q := range accepted {
+			prices = append(prices, q.price)
+			if q.time.Before(oldest) {
+				oldest = q.time
+			}
+		}
+		index = append(index, models.PriceResponse{Symbol: symbol, Price: median(prices).String(), Time: oldest})
+	}
+
+	return index, nil
+}
+
+// poll returns prices of sources which answered, failed sources are logged and skipped
+func (a *Aggregator) poll() map[string][]models.PriceResponse {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string][]models.PriceResponse, len(a.sources))
+	)
+	for _, source := range a.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prices, err := source.GetPrice()
+			if err != nil {
+				a.log.Warn("price source is unavailable", "source", source.Name(), "error", err)
+				return
+			}
+			mu.Lock()
+			results[source.Name()] = prices
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// rejectOutliers keeps quotes within maxDeviation of median
+func rejectOutliers(quotes []quote, maxDeviation decimal.Decimal) []quote {
+	prices := make([]decimal.Decimal, 0, len(quotes))
+	for _, q := range quotes {
+		prices = append(prices, q.price)
+	}
+	m := median(prices)
+
+	accepted := make([]quote, 0, len(quotes))
+	for _, q := range quotes {
+		if q.price.Sub(m).Abs().Div(m).LessThanOrEqual(maxDeviation) {
+			accepted = append(accepted, q)
+		}
+	}
+	return accepted
+}
+
+func median(prices []decimal.Decimal) decimal.Decimal {
+	sorted := slices.Clone(prices)
+	slices.SortFunc(sorted, func(a, b decimal.Decimal) int { return a.Cmp(b) })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return sorted[n/2-1].Add(sorted[n/2]).Div(decimal.NewFromInt(2))
+}
@@ -0,0 +1,204 @@
+package price
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+)
+
+// stubSource quotes BTCUSDT at price, or fails when err is set
+type stubSource struct {
+	name  string
+	price string
+	time  time.Time
+	err   error
+}
+
+func (s stubSource) Name() string {
+	return s.name
+}
+
+func (s stubSource) GetPrice() ([]models.PriceResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []models.PriceResponse{{Symbol: "BTCUSDT", Price: s.price, Time: s.time}}, nil
+}
+
+var errDown = errors.New("connection refused")
+
+func TestAggregatorGetPrice(t *testing.T) {
+	now := time.UnixMilli(1733486400000)
+	cfg := config.IndexConfig{Interval: time.Second, MaxDeviation: 0.02, MinSources: 2}
+
+	tests := []struct {
+		name    string
+		sources []Source
+		// want is index price, empty if ticker gets none
+		want     string
+		wantTime time.Time
+		wantErr  error
+	}{
+		{
+			name: "odd number of sources",
+			sources: []Source{
+				stubSource{name: "binance", price: "100", time: now},
+				stubSource{name: "bybit", price: "101", time: now},
+				stubSource{name: "okx", price: "99.5", time: now},
+			},
+			want:     "100",
+			wantTime: now,
+		},
+		{
+			name: "even number of sources",
+			sources: []Source{
+				stubSource{name: "binance", price: "100", time: now},
+				stubSource{name: "bybit", price: "101", time: now},
+			},
+			want:     "100.5",
+			wantTime: now,
+		},
+		{
+			name: "outlier rejected",
+			sources: []Source{
+				stubSource{name: "binance", price: "100", time: now},
+				stubSource{name: "bybit", price: "101", time: now},
+				stubSource{name: "okx", price: "99", time: now},
+				stubSource{name: "broken", price: "150", time: now},
+			},
+			want:     "100",
+			wantTime: now,
+		},
+		{
+			name: "source down",
+			sources: []Source{
+				stubSource{name: "binance", price: "100", time: now},
+				stubSource{name: "bybit", err: errDown},
+				stubSource{name: "okx", price: "102", time: now},
+			},
+			want:     "101",
+			wantTime: now,
+		},
+		{
+			name: "index is as old as oldest quote",
+			sources: []Source{
+				stubSource{name: "binance", price: "100", time: now},
+				stubSource{name: "okx", price: "100", time: now.Add(-3 * time.Second)},
+			},
+			want:     "100",
+			wantTime: now.Add(-3 * time.Second),
+		},
+		{
+			name: "invalid quote skipped",
+			sources: []Source{
+				stubSource{name: "binance", price: "100", time: now},
+				stubSource{name: "bybit", price: "abc", time: now},
+				stubSource{name: "okx", price: "-1", time: now},
+				stubSource{name: "kraken", price: "102", time: now},
+			},
+			want:     "101",
+			wantTime: now,
+		},
+		{
+			name: "not enough agreeing sources",
+			sources: []Source{
+				stubSource{name: "binance", price: "100", time: now},
+				stubSource{name: "bybit", price: "200", time: now},
+			},
+		},
+		{
+			name: "single source left",
+			sources: []Source{
+				stubSource{name: "binance", price: "100", time: now},
+				stubSource{name: "bybit", err: errDown},
+			},
+		},
+		{
+			name: "all sources down",
+			sources: []Source{
+				stubSource{name: "binance", err: errDown},
+				stubSource{name: "bybit", err: errDown},
+			},
+			wantErr: ErrNoSources,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAggregator(*slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, tt.sources...)
+			got, err := a.GetPrice()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if tt.want == "" {
+				if len(got) != 0 {
+					t.Fatalf("got %v, want no index price", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %v, want one index price", got)
+			}
+			if got[0].Symbol != "BTCUSDT" || got[0].Price != tt.want {
+				t.Errorf("got %s %s, want BTCUSDT %s", got[0].Symbol, got[0].Price, tt.want)
+			}
+			if !got[0].Time.Equal(tt.wantTime) {
+				t.Errorf("got time %v, want %v", got[0].Time, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestAggregatorGetPriceOfEverySymbol(t *testing.T) {
+	now := time.Now()
+	multi := multiSource{
+		"binance": {{Symbol: "BTCUSDT", Price: "100", Time: now}, {Symbol: "ETHUSDT", Price: "10", Time: now}},
+		"bybit":   {{Symbol: "BTCUSDT", Price: "102", Time: now}, {Symbol: "ETHUSDT", Price: "10.1", Time: now}},
+		// only one source quotes SOLUSDT
+		"okx": {{Symbol: "SOLUSDT", Price: "5", Time: now}},
+	}
+	cfg := config.IndexConfig{Interval: time.Second, MaxDeviation: 0.02, MinSources: 2}
+	a := NewAggregator(*slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, multi.sources()...)
+
+	got, err := a.GetPrice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Symbol < got[j].Symbol })
+	if len(got) != 2 || got[0].Symbol != "BTCUSDT" || got[0].Price != "101" || got[1].Symbol != "ETHUSDT" || got[1].Price != "10.05" {
+		t.Fatalf("got %v, want BTCUSDT 101 and ETHUSDT 10.05", got)
+	}
+}
+
+// multiSource is set of sources by name quoting several symbols
+type multiSource map[string][]models.PriceResponse
+
+func (m multiSource) sources() []Source {
+	sources := make([]Source, 0, len(m))
+	for name, prices := range m {
+		sources = append(sources, namedSource{name: name, prices: prices})
+	}
+	return sources
+}
+
+type namedSource struct {
+	name   string
+	prices []models.PriceResponse
+}
+
+func (s namedSource) Name() string {
+	return s.name
+}
+
+func (s namedSource) GetPrice() ([]models.PriceResponse, error) {
+	return s.prices, nil
+}
@@ -0,0 +1,129 @@
+package price
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"Exchange/internal/marketdata"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// markPrecision is number of decimal places of mark price
+const markPrecision = 8
+
+type quoteReader interface {
+	GetQuote(ctx context.Context, ticker string) (models.Quote, error)
+}
+
+// Mark computes mark price as index price plus EMA of basis (last − index).
+// Single bad print moves mark by basis weight only, while persistent premium
+// of last price over index is followed. Without index basis sample is zero,
+// so mark comes back to last price. Mark is as old as last price it follows,
+// symbol whose index is stale gets no mark until index is updated
+type Mark struct {
+	log     slog.Logger
+	cfg     config.MarkConfig
+	symbols []string
+	last    quoteReader
+	index   quoteReader
+	guard   *marketdata.Guard
+	// indexInterval is how often index is updated, index is older than last price by up to that much
+	indexInterval time.Duration
+	// basis is touched only by Run goroutine
+	basis map[string]decimal.Decimal
+}
+
+func NewMark(log slog.Logger,
+	cfg config.MarkConfig,
+	symbols []string,
+	last, index quoteReader,
+	guard *marketdata.Guard,
+	indexInterval time.Duration) *Mark {
+	return &Mark{
+		log:           log,
+		cfg:           cfg,
+		symbols:       symbols,
+		last:          last,
+		index:         index,
+		guard:         guard,
+		indexInterval: indexInterval,
+		basis:         make(map[string]decimal.Decimal, len(symbols)),
+	}
+}
+
+// Run passes mark prices to onPrices every interval until ctx is done
+func (m *Mark) Run(ctx context.Context, onPrices func([]models.PriceResponse)) {
+	const op = "price.Mark.Run"
+	log := m.log.With("op", op)
+	if m.cfg.Interval <= 0 || m.cfg.BasisPeriod <= 0 {
+		log.Error("interval and basis period must be positive", "interval", m.cfg.Interval, "basis_period", m.cfg.BasisPeriod)
+		return
+	}
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if prices := m.update(ctx); len(prices) > 0 {
+			onPrices(prices)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update samples basis of every symbol and returns new mark prices
+func (m *Mark) update(ctx context.Context) []models.PriceResponse {
+	alpha := decimal.NewFromInt(2).Div(decimal.NewFromInt(int64(m.cfg.BasisPeriod) + 1))
+
+	now := time.Now()
+	prices := make([]models.PriceResponse, 0, len(m.symbols))
+	for _, symbol := range m.symbols {
+		last, lastTime, err := readQuote(ctx, m.last, symbol)
+		if err != nil {
+			m.log.Debug("no last price for mark", "symbol", symbol, "error", err)
+			continue
+		}
+		index, indexTime, err := readQuote(ctx, m.index, symbol)
+		if err != nil {
+			index = last
+		} else {
+			ticker := strings.TrimSuffix(symbol, "USDT") + "/USDT"
+			// index is checked as of its previous update, it is not refreshed more often
+			if err := m.guard.Check(ticker, models.Quote{Time: indexTime}, now.Add(-m.indexInterval)); err != nil {
+				m.log.Warn("stale index, mark is not updated", "symbol", symbol, "error", err)
+				continue
+			}
+		}
+
+		basis := m.basis[symbol]
+		basis = basis.Add(alpha.Mul(last.Sub(index).Sub(basis)))
+		m.basis[symbol] = basis
+
+		mark := index.Add(basis).Round(markPrecision)
+		prices = append(prices, models.PriceResponse{Symbol: symbol, Price: mark.String(), Time: lastTime})
+	}
+	return prices
+}
+
+func readQuote(ctx context.Context, source quoteReader, symbol string) (decimal.Decimal, time.Time, error) {
+	quote, err := source.GetQuote(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, time.Time{}, err
+	}
+	price, err := decimal.NewFromString(quote.Price)
+	if err != nil {
+		return decimal.Zero, time.Time{}, err
+	}
+	if !price.IsPositive() {
+		return decimal.Zero, time.Time{}, fmt.Errorf("non-positive price %s", quote.Price)
+	}
+	return price, quote.Time, nil
+}
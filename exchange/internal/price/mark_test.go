@@ -0,0 +1,63 @@
+package price
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"Exchange/internal/marketdata"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// stubQuotes serves stored quotes by symbol, missing symbol has no price
+type stubQuotes map[string]models.Quote
+
+func (s stubQuotes) GetQuote(_ context.Context, ticker string) (models.Quote, error) {
+	quote, ok := s[ticker]
+	if !ok {
+		return models.Quote{}, errors.New("price not found")
+	}
+	return quote, nil
+}
+
+func TestMarkUpdate(t *testing.T) {
+	now := time.Now()
+	last := stubQuotes{"BTCUSDT": {Price: "62000", Time: now}}
+	guard := marketdata.NewGuard(config.MarketDataConfig{MaxPriceAge: 5 * time.Second})
+
+	tests := []struct {
+		name     string
+		index    stubQuotes
+		wantMark string
+	}{
+		{name: "fresh index", index: stubQuotes{"BTCUSDT": {Price: "61000", Time: now.Add(-time.Second)}}, wantMark: "61064.51612903"},
+		{name: "index one update behind", index: stubQuotes{"BTCUSDT": {Price: "61000", Time: now.Add(-8 * time.Second)}}, wantMark: "61064.51612903"},
+		{name: "no index", index: stubQuotes{}, wantMark: "62000"},
+		{name: "stale index", index: stubQuotes{"BTCUSDT": {Price: "61000", Time: now.Add(-time.Minute)}}},
+		{name: "index without time", index: stubQuotes{"BTCUSDT": {Price: "61000"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMark(*slog.New(slog.NewTextHandler(io.Discard, nil)), config.MarkConfig{Interval: time.Second, BasisPeriod: 30},
+				[]string{"BTCUSDT"}, last, tt.index, guard, 5*time.Second)
+			prices := m.update(context.Background())
+
+			if tt.wantMark == "" {
+				if len(prices) != 0 {
+					t.Fatalf("got marks %v, want none", prices)
+				}
+				return
+			}
+			if len(prices) != 1 || prices[0].Price != tt.wantMark {
+				t.Fatalf("got marks %v, want %s", prices, tt.wantMark)
+			}
+			if !prices[0].Time.Equal(now) {
+				t.Errorf("got mark time %v, want last price time %v", prices[0].Time, now)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// Package risk implements isolated margin liquidation model with tiered
+// maintenance margin brackets, shared by trade service and simulations.
+package risk
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+var (
+	ErrNoBrackets       = errors.New("maintenance margin brackets are empty")
+	ErrLeverageTooHigh  = errors.New("leverage exceeds bracket limit")
+	ErrInvalidPosition  = errors.New("invalid position")
+	ErrInvalidBracket   = errors.New("invalid maintenance margin bracket")
+	ErrUnlimitedBracket = errors.New("only the last bracket can be unlimited")
+)
+
+// Bracket is maintenance margin tier for position notional up to NotionalCap (zero - unlimited)
+type Bracket struct {
+	NotionalCap decimal.Decimal
+	MaxLeverage uint8
+	MMR         decimal.Decimal
+	// MaintenanceAmount makes maintenance margin continuous across tiers
+	MaintenanceAmount decimal.Decimal
+}
+
+type pairRisk struct {
+	brackets           []Bracket
+	liquidationFeeRate decimal.Decimal
+}
+
+// Position is what model needs to know about isolated position
+type Position struct {
+	Type       models.OrderType
+	EntryPrice decimal.Decimal
+	// Size is position notional at entry price
+	Size decimal.Decimal
+	// Margin is isolated margin backing position including extra margin
+	Margin decimal.Decimal
+}
+
+// PositionOf builds Position from order
+func PositionOf(order models.Order) Position {
+	return Position{
+		Type:       order.Type,
+		EntryPrice: order.EntryPrice,
+		Size:       order.Margin.Mul(decimal.NewFromInt(int64(order.Leverage))),
+		Margin:     order.Margin.Add(order.ExtraMargin),
+	}
+}
+
+type Model struct {
+	def   pairRisk
+	pairs map[string]pairRisk
+}
+
+func New(cfg config.RiskConfig) (*Model, error) {
+	def, err := newPairRisk(cfg.Brackets, cfg.LiquidationFeeRate)
+	if err != nil {
+		return nil, fmt.Errorf("default brackets: %w", err)
+	}
+
+	pairs := make(map[string]pairRisk, len(cfg.Pairs))
+	for ticker, pairCfg := range cfg.Pairs {
+		brackets := pairCfg.Brackets
+		if len(brackets) == 0 {
+			brackets = cfg.Brackets
+		}
+		feeRate := pairCfg.LiquidationFeeRate
+		if feeRate == 0 {
+			feeRate = cfg.LiquidationFeeRate
+		}
+
+		pair, err := newPairRisk(brackets, feeRate)
+		if err != nil {
+			return nil, fmt.Errorf("%s brackets: %w", ticker, err)
+		}
+		pairs[ticker] = pair
+	}
+
+	return &Model{def: def, pairs: pairs}, nil
+}
+
+func newPairRisk(cfg []config.BracketConfig, feeRate float64) (pairRisk, error) {
+	if len(cfg) == 0 {
+		return pairRisk{}, ErrNoBrackets
+	}
+
+	brackets := make([]Bracket, 0, len(cfg))
+	for i, b := range cfg {
+		if b.MMR < 0 || b.MMR >= 1 || b.MaxLeverage == 0 || b.NotionalCap < 0 {
+			return pairRisk{}, ErrInvalidBracket
+		}
+		if b.NotionalCap == 0 && i != len(cfg)-1 {
+			return pairRisk{}, ErrUnlimitedBracket
+		}
+		brackets = append(brackets, Bracket{
+			NotionalCap: decimal.NewFromFloat(b.NotionalCap),
+			MaxLeverage: b.MaxLeverage,
+			MMR:         decimal.NewFromFloat(b.MMR),
+		})
+	}
+	sort.SliceStable(brackets, func(i, j int) bool {
+		if brackets[i].NotionalCap.IsZero() {
+			return false
+		}
+		return brackets[j].NotionalCap.IsZero() || brackets[i].NotionalCap.LessThan(brackets[j].NotionalCap)
+	})
+
+	// maintenance amount of tier: previous amount + tier floor * (mmr - previous mmr)
+	for i := 1; i < len(brackets); i++ {
+		prev := brackets[i-1]
+		brackets[i].MaintenanceAmount = prev.MaintenanceAmount.Add(prev.NotionalCap.Mul(brackets[i].MMR.Sub(prev.MMR)))
+	}
+
+	return pairRisk{brackets: brackets, liquidationFeeRate: decimal.NewFromFloat(feeRate)}, nil
+}
+
+func (m *Model) pair(ticker string) pairRisk {
+	if pair, ok := m.pairs[ticker]; ok {
+		return pair
+	}
+	return m.def
+}
+
+// Bracket returns maintenance margin tier of position notional
+func (m *Model) Bracket(ticker string, notional decimal.Decimal) Bracket {
+	brackets := m.pair(ticker).brackets
+	for _, b := range brackets {
+		if b.NotionalCap.IsZero() || notional.LessThanOrEqual(b.NotionalCap) {
+			return b
+		}
+	}
+	return brackets[len(brackets)-1]
+}
+
+// CheckLeverage refuses leverage above limit of position's bracket
+func (m *Model) CheckLeverage(ticker string, notional decimal.Decimal, leverage uint8) error {
+	if leverage > m.Bracket(ticker, notional).MaxLeverage {
+		return ErrLeverageTooHigh
+	}
+	return nil
+}
+
+// MaintenanceMargin returns margin position must keep at mark price to stay open
+func (m *Model) MaintenanceMargin(ticker string, notional decimal.Decimal) decimal.Decimal {
+	b := m.Bracket(ticker, notional)
+	return notional.Mul(b.MMR).Sub(b.MaintenanceAmount)
+}
+
+// LiquidationPrice returns price where position equity falls to maintenance margin.
+// Long: (size - margin - amount) / (qty * (1 - mmr)); short: (size + margin + amount) / (qty * (1 + mmr))
+func (m *Model) LiquidationPrice(ticker string, p Position) (decimal.Decimal, error) {
+	if !p.EntryPrice.IsPositive() || !p.Size.IsPositive() || p.Margin.IsNegative() {
+		return decimal.Zero, ErrInvalidPosition
+	}
+
+	b := m.Bracket(ticker, p.Size)
+	qty := p.Size.Div(p.EntryPrice)
+	one := decimal.NewFromInt(1)
+
+	var price decimal.Decimal
+	if p.Type == models.Long {
+		price = p.Size.Sub(p.Margin).Sub(b.MaintenanceAmount).Div(qty.Mul(one.Sub(b.MMR)))
+	} else {
+		price = p.Size.Add(p.Margin).Add(b.MaintenanceAmount).Div(qty.Mul(one.Add(b.MMR)))
+	}
+
+	if price.IsNegative() {
+		return decimal.Zero, nil
+	}
+	return price, nil
+}
+
+// BankruptcyPrice returns price where position equity is exactly zero
+func (m *Model) BankruptcyPrice(p Position) decimal.Decimal {
+	qty := p.Size.Div(p.EntryPrice)
+	if p.Type == models.Long {
+		return decimal.Max(p.Size.Sub(p.Margin).Div(qty), decimal.Zero)
+	}
+	return p.Size.Add(p.Margin).Div(qty)
+}
+
+// Equity returns margin plus unrealized pnl of position at price
+func (m *Model) Equity(p Position, price decimal.Decimal) decimal.Decimal {
+	qty := p.Size.Div(p.EntryPrice)
+	pnl := price.Sub(p.EntryPrice).Mul(qty)
+	if p.Type == models.Short {
+		pnl = pnl.Neg()
+	}
+	return p.Margin.Add(pnl)
+}
+
+// LiquidationFee returns fee charged from position liquidated at price
+func (m *Model) LiquidationFee(ticker string, p Position, price decimal.Decimal) decimal.Decimal {
+	qty := p.Size.Div(p.EntryPrice)
+	return qty.Mul(price).Mul(m.pair(ticker).liquidationFeeRate)
+}
+
+// Settlement splits equity of liquidated position: fee and surplus go to
+// insurance fund, Loss is what fund covers when price moved past bankruptcy price
+type Settlement struct {
+	Equity  decimal.Decimal
+	Fee     decimal.Decimal
+	Surplus decimal.Decimal
+	Loss    decimal.Decimal
+}
+
+// Settle settles position liquidated at price, amounts are rounded to cents
+func (m *Model) Settle(ticker string, p Position, price decimal.Decimal) Settlement {
+	equity := m.Equity(p, price).Round(2)
+	if equity.IsNegative() {
+		return Settlement{Equity: equity, Loss: equity.Neg()}
+	}
+
+	fee := decimal.Min(m.LiquidationFee(ticker, p, price).Round(2), equity)
+	return Settlement{Equity: equity, Fee: fee, Surplus: equity.Sub(fee)}
+}
@@ -0,0 +1,321 @@
+package candles
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCandlesLimit = 500
+	MaxCandlesLimit     = 1000
+	// klinesLimit is max number of bars Binance returns per request
+	klinesLimit = 1000
+)
+
+var (
+	ErrInvalidInterval = errors.New("invalid interval")
+	ErrInvalidPeriod   = errors.New("from must be before to")
+	ErrInvalidPrice    = errors.New("invalid price")
+)
+
+// Interval is length of bar, names are the ones Binance klines use
+type Interval struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Intervals are bar lengths built for every ticker
+var Intervals = []Interval{
+	{Name: "1m", Duration: time.Minute},
+	{Name: "5m", Duration: 5 * time.Minute},
+	{Name: "15m", Duration: 15 * time.Minute},
+	{Name: "1h", Duration: time.Hour},
+	{Name: "1d", Duration: 24 * time.Hour},
+}
+
+// IntervalOf returns interval by name (1m, 1h, ...)
+func IntervalOf(name string) (Interval, bool) {
+	for _, interval := range Intervals {
+		if interval.Name == name {
+			return interval, true
+		}
+	}
+	return Interval{}, false
+}
+
+type Candles struct {
+	log     slog.Logger
+	cfg     config.CandlesConfig
+	manager Manager
+	klines  klinesSource
+	symbols []string
+
+	mu sync.Mutex
+	// bars are bars being built, one per ticker and interval
+	bars map[barKey]models.Candle
+	// pending are bars changed since last flush, rolled over bar stays here until saved
+	pending map[pendingKey]models.Candle
+}
+
+type barKey struct {
+	ticker   string
+	interval string
+}
+
+type pendingKey struct {
+	barKey
+	openTime int64
+}
+
+type Manager interface {
+	SaveCandles(ctx context.Context, candles []models.Candle) error
+	ReplaceCandles(ctx context.Context, candles []models.Candle) error
+	GetLastCandleTime(ctx context.Context, ticker, interval string) (time.Time, error)
+	GetCandles(ctx context.Context, ticker, interval string, from, to time.Time, limit int) ([]models.Candle, error)
+	GetCandlesBefore(ctx context.Context, ticker, interval string, to time.Time, limit int) ([]models.Candle, error)
+}
+
+type klinesSource interface {
+	GetKlines(symbol, interval string, start time.Time, limit int) ([]models.Candle, error)
+}
+
+// New builds candles of price ticks. Bars of symbols (BTCUSDT) can be backfilled from klines
+func New(log slog.Logger, cfg config.CandlesConfig, manager Manager, klines klinesSource, symbols []string) *Candles {
+	return &Candles{
+		log:     log,
+		cfg:     cfg,
+		manager: manager,
+		klines:  klines,
+		symbols: symbols,
+		bars:    make(map[barKey]models.Candle),
+		pending: make(map[pendingKey]models.Candle),
+	}
+}
+
+// HandlePrice adds tick of ticker (BTC/USDT) quoted at tickTime to bars of every interval.
+// Tick older than bar being built is dropped
+func (c *Candles) HandlePrice(ticker, price string, tickTime time.Time) error {
+	const op = "candles.HandlePrice"
+
+	priceDec, err := decimal.NewFromString(price)
+	if err != nil || !priceDec.IsPositive() {
+		return fmt.Errorf("%s: %w: %q", op, ErrInvalidPrice, price)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, interval := range Intervals {
+		key := barKey{ticker: ticker, interval: interval.Name}
+		openTime := tickTime.UTC().Truncate(interval.Duration)
+
+		bar, ok := c.bars[key]
+		switch {
+		case !ok || bar.OpenTime.Before(openTime):
+			bar = models.Candle{
+				Ticker:   ticker,
+				Interval: interval.Name,
+				OpenTime: openTime,
+				Open:     priceDec,
+				High:     priceDec,
+				Low:      priceDec,
+				Close:    priceDec,
+			}
+		case openTime.Before(bar.OpenTime):
+			continue
+		default:
+			bar.High = decimal.Max(bar.High, priceDec)
+			bar.Low = decimal.Min(bar.Low, priceDec)
+			bar.Close = priceDec
+		}
+
+		c.bars[key] = bar
+		c.pending[pendingKey{barKey: key, openTime: openTime.Unix()}] = bar
+	}
+
+	return nil
+}
+
+// Run saves changed bars every flush interval until ctx is done
+func (c *Candles) Run(ctx context.Context) {
+	const op = "candles.Run"
+	log := c.log.With("op", op)
+	if c.cfg.FlushInterval <= 0 {
+		log.Error("flush interval must be positive", "interval", c.cfg.FlushInterval)
+		return
+	}
+
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// last bars are saved on shutdown too
+			if err := c.Flush(context.WithoutCancel(ctx)); err != nil {
+				log.Error("candles flush failed", "error", err)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.Flush(ctx); err != nil {
+			log.Error("candles flush failed", "error", err)
+		}
+	}
+}
+
+// Flush saves bars changed since last flush, failed bars are retried by next flush
+func (c *Candles) Flush(ctx context.Context) error {
+	const op = "candles.Flush"
+
+	c.mu.Lock()
+	batch := c.pending
+	c.pending = make(map[pendingKey]models.Candle)
+	c.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	candles := make([]models.Candle, 0, len(batch))
+	for _, candle := range batch {
+		candles = append(candles, candle)
+	}
+	if err := c.manager.SaveCandles(ctx, candles); err != nil {
+		c.mu.Lock()
+		for key, candle := range batch {
+			// bar could be updated by newer tick meanwhile
+			if _, ok := c.pending[key]; !ok {
+				c.pending[key] = candle
+			}
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Backfill loads bars of backfill period missing in storage from klines.
+// Latest stored bar is loaded again, it could be saved before it was closed
+func (c *Candles) Backfill(ctx context.Context) error {
+	const op = "candles.Backfill"
+	log := c.log.With("op", op)
+
+	var errs []error
+	for _, symbol := range c.symbols {
+		ticker := strings.TrimSuffix(symbol, "USDT") + "/USDT"
+		for _, interval := range Intervals {
+			loaded, err := c.backfill(ctx, symbol, ticker, interval)
+			if err != nil {
+				log.Error("candles backfill failed", "ticker", ticker, "interval", interval.Name, "error", err)
+				errs = append(errs, fmt.Errorf("%s %s: %w", ticker, interval.Name, err))
+				continue
+			}
+			log.Info("candles backfilled", "ticker", ticker, "interval", interval.Name, "candles", loaded)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %w", op, errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c *Candles) backfill(ctx context.Context, symbol, ticker string, interval Interval) (int, error) {
+	since := time.Now().UTC().Add(-c.cfg.BackfillPeriod).Truncate(interval.Duration)
+	last, err := c.manager.GetLastCandleTime(ctx, ticker, interval.Name)
+	if err != nil {
+		return 0, err
+	}
+	if last.After(since) {
+		since = last
+	}
+
+	loaded := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return loaded, err
+		}
+
+		candles, err := c.klines.GetKlines(symbol, interval.Name, since, klinesLimit)
+		if err != nil {
+			return loaded, err
+		}
+		if len(candles) == 0 {
+			return loaded, nil
+		}
+		for i := range candles {
+			candles[i].Ticker = ticker
+			candles[i].Interval = interval.Name
+		}
+		if err := c.manager.ReplaceCandles(ctx, candles); err != nil {
+			return loaded, err
+		}
+		loaded += len(candles)
+
+		if len(candles) < klinesLimit {
+			return loaded, nil
+		}
+		since = candles[len(candles)-1].OpenTime.Add(interval.Duration)
+	}
+}
+
+// GetCandles returns up to limit bars of ticker opened in [from, to), oldest first.
+// Missing to is now, missing from is limit bars before to
+func (c *Candles) GetCandles(ctx context.Context, ticker, interval string, from, to time.Time, limit int) ([]models.Candle, error) {
+	const op = "candles.GetCandles"
+
+	i, ok := IntervalOf(interval)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrInvalidInterval, interval)
+	}
+	if limit <= 0 {
+		limit = DefaultCandlesLimit
+	}
+	limit = min(limit, MaxCandlesLimit)
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-time.Duration(limit) * i.Duration)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidPeriod)
+	}
+
+	candles, err := c.manager.GetCandles(ctx, ticker, interval, from, to, limit)
+	if err != nil {
+		c.log.Error("failed to get candles", "ticker", ticker, "interval", interval, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return candles, nil
+}
+
+// GetCandlesBefore returns up to limit latest bars of ticker opened before to, oldest first
+func (c *Candles) GetCandlesBefore(ctx context.Context, ticker, interval string, to time.Time, limit int) ([]models.Candle, error) {
+	const op = "candles.GetCandlesBefore"
+
+	if _, ok := IntervalOf(interval); !ok {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrInvalidInterval, interval)
+	}
+	if limit <= 0 {
+		limit = DefaultCandlesLimit
+	}
+	limit = min(limit, MaxCandlesLimit)
+
+	candles, err := c.manager.GetCandlesBefore(ctx, ticker, interval, to, limit)
+	if err != nil {
+		c.log.Error("failed to get candles", "ticker", ticker, "interval", interval, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return candles, nil
+}
@@ -0,0 +1,213 @@
+package chart
+
+import (
+	"Exchange/internal/domain/models"
+	"Exchange/internal/services/candles"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSearchLimit = 30
+	MaxMarksLimit      = 1000
+	// maxPriceDecimals is scale of candle prices, symbol without candles is shown with all of them
+	maxPriceDecimals = 8
+	minPriceDecimals = 2
+	// priceSignificantDigits is how many digits of price chart shows at least
+	priceSignificantDigits = 6
+)
+
+var (
+	ErrUnknownSymbol     = errors.New("unknown symbol")
+	ErrInvalidResolution = errors.New("invalid resolution")
+)
+
+// Resolutions maps chart resolutions to candle intervals, in ascending order
+var Resolutions = []Resolution{
+	{Name: "1", Interval: "1m"},
+	{Name: "5", Interval: "5m"},
+	{Name: "15", Interval: "15m"},
+	{Name: "60", Interval: "1h"},
+	{Name: "1D", Interval: "1d"},
+}
+
+type Resolution struct {
+	Name     string
+	Interval string
+}
+
+// ResolutionOf returns resolution by name, D is the same as 1D
+func ResolutionOf(name string) (Resolution, bool) {
+	if name == "D" {
+		name = "1D"
+	}
+	for _, resolution := range Resolutions {
+		if resolution.Name == name {
+			return resolution, true
+		}
+	}
+	return Resolution{}, false
+}
+
+type Chart struct {
+	log     slog.Logger
+	manager Manager
+	candles candleReader
+}
+
+type Manager interface {
+	GetTradingPairs(ctx context.Context) ([]models.TradingPair, error)
+	GetUserChartMarks(ctx context.Context, userId int64, ticker string, from, to time.Time, limit int) ([]models.ChartMark, error)
+}
+
+type candleReader interface {
+	GetCandlesBefore(ctx context.Context, ticker, interval string, to time.Time, limit int) ([]models.Candle, error)
+}
+
+func New(log slog.Logger, manager Manager, candles candleReader) *Chart {
+	return &Chart{
+		log:     log,
+		manager: manager,
+		candles: candles,
+	}
+}
+
+// Symbols returns tickers (BTC/USDT) of all trading pairs
+func (c *Chart) Symbols(ctx context.Context) ([]string, error) {
+	const op = "chart.Symbols"
+
+	pairs, err := c.manager.GetTradingPairs(ctx)
+	if err != nil {
+		c.log.Error("failed to get trading pairs", "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	symbols := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		symbols = append(symbols, pair.BaseAsset+"/"+pair.QuoteAsset)
+	}
+	return symbols, nil
+}
+
+// ResolveSymbol returns ticker of trading pair named BTC/USDT, BTCUSDT
+// or prefixed with exchange (EXCHANGE:BTC/USDT)
+func (c *Chart) ResolveSymbol(ctx context.Context, name string) (string, error) {
+	const op = "chart.ResolveSymbol"
+
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	symbols, err := c.Symbols(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	for _, symbol := range symbols {
+		if symbol == name || strings.ReplaceAll(symbol, "/", "") == name {
+			return symbol, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w: %q", op, ErrUnknownSymbol, name)
+}
+
+// Search returns up to limit tickers containing query, BTC finds BTC/USDT
+func (c *Chart) Search(ctx context.Context, query string, limit int) ([]string, error) {
+	const op = "chart.Search"
+
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	query = strings.ToUpper(strings.TrimSpace(query))
+
+	symbols, err := c.Symbols(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	found := []string{}
+	for _, symbol := range symbols {
+		if len(found) == limit {
+			break
+		}
+		if strings.Contains(symbol, query) || strings.Contains(strings.ReplaceAll(symbol, "/", ""), query) {
+			found = append(found, symbol)
+		}
+	}
+	return found, nil
+}
+
+// History returns bars of ticker opened in [from, to), or countback bars opened
+// before to when countback is set. Long range is cut to latest candles.MaxCandlesLimit bars,
+// chart asks for earlier ones itself. Without bars it returns open time of latest bar
+// before from, zero if there is none
+func (c *Chart) History(ctx context.Context, ticker, resolution string, from, to time.Time, countback int) ([]models.Candle, time.Time, error) {
+	const op = "chart.History"
+
+	r, ok := ResolutionOf(resolution)
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("%s: %w: %q", op, ErrInvalidResolution, resolution)
+	}
+
+	limit := countback
+	if limit <= 0 {
+		limit = candles.MaxCandlesLimit
+	}
+	bars, err := c.candles.GetCandlesBefore(ctx, ticker, r.Interval, to, limit)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if countback <= 0 {
+		start := sort.Search(len(bars), func(i int) bool { return !bars[i].OpenTime.Before(from) })
+		bars = bars[start:]
+	}
+	if len(bars) > 0 {
+		return bars, time.Time{}, nil
+	}
+
+	prev, err := c.candles.GetCandlesBefore(ctx, ticker, r.Interval, from, 1)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(prev) == 0 {
+		return bars, time.Time{}, nil
+	}
+	return bars, prev[0].OpenTime, nil
+}
+
+// Pricescale returns 10^decimals of ticker's price for chart: enough decimals to show
+// priceSignificantDigits of latest close, so 62000.12 gets 2 and 0.1234 gets 6
+func (c *Chart) Pricescale(ctx context.Context, ticker string) (int, error) {
+	const op = "chart.Pricescale"
+
+	decimals := int32(maxPriceDecimals)
+	bars, err := c.candles.GetCandlesBefore(ctx, ticker, Resolutions[len(Resolutions)-1].Interval, time.Now(), 1)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(bars) > 0 && bars[0].Close.IsPositive() {
+		// digits before decimal point, negative for leading zeros after it
+		intDigits := int32(bars[0].Close.NumDigits()) + bars[0].Close.Exponent()
+		decimals = min(max(priceSignificantDigits-intDigits, minPriceDecimals), maxPriceDecimals)
+	}
+
+	return int(decimal.New(1, decimals).IntPart()), nil
+}
+
+// Marks returns user's entries, exits and liquidations on ticker made in [from, to)
+func (c *Chart) Marks(ctx context.Context, userId int64, ticker string, from, to time.Time) ([]models.ChartMark, error) {
+	const op = "chart.Marks"
+
+	marks, err := c.manager.GetUserChartMarks(ctx, userId, ticker, from, to, MaxMarksLimit)
+	if err != nil {
+		c.log.Error("failed to get chart marks", "userId", userId, "ticker", ticker, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return marks, nil
+}
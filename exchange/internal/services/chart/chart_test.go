@@ -0,0 +1,50 @@
+package chart
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"github.com/shopspring/decimal"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// lastClose serves one daily bar closed at price, none if price is empty
+type lastClose string
+
+func (c lastClose) GetCandlesBefore(ctx context.Context, ticker, interval string, to time.Time, limit int) ([]models.Candle, error) {
+	if c == "" {
+		return []models.Candle{}, nil
+	}
+	// candles are stored with scale 8
+	return []models.Candle{{Ticker: ticker, Interval: interval, Close: decimal.RequireFromString(string(c)).Round(8)}}, nil
+}
+
+func TestPricescale(t *testing.T) {
+	tests := []struct {
+		close string
+		want  int
+	}{
+		{close: "62000.12", want: 100},
+		{close: "3500.5", want: 100},
+		{close: "150.123", want: 1000},
+		{close: "1.05", want: 100000},
+		{close: "0.1234", want: 1000000},
+		{close: "0.00001234", want: 100000000},
+		{close: "", want: 100000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.close, func(t *testing.T) {
+			c := New(*slog.New(slog.NewTextHandler(io.Discard, nil)), nil, lastClose(tt.close))
+			got, err := c.Pricescale(context.Background(), "BTC/USDT")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got pricescale %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package funding
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"Exchange/internal/risk"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPaymentsLimit = 100
+	MaxPaymentsLimit     = 1000
+)
+
+var ErrInvalidPrice = errors.New("invalid price")
+
+type Funding struct {
+	log     slog.Logger
+	cfg     config.FundingConfig
+	manager Manager
+	index   priceSource
+	mark    priceSource
+	risk    *risk.Model
+	orders  orderIndex
+}
+
+type Manager interface {
+	GetOpenOrders(ctx context.Context) ([]models.Order, error)
+	ApplyFundingPayments(ctx context.Context, payments []models.FundingPayment,
+		liquidationPrice func(models.Order) (decimal.Decimal, error)) (int, []models.Order, error)
+	GetUserFundingPayments(ctx context.Context, userId int64, limit, offset int) ([]models.FundingPayment, error)
+}
+
+type priceSource interface {
+	GetPrice(ctx context.Context, ticker string) (string, error)
+}
+
+// orderIndex keeps liquidation index of open orders
+type orderIndex interface {
+	SaveOrder(ctx context.Context, order models.Order) error
+}
+
+func New(log slog.Logger, cfg config.FundingConfig, manager Manager, index, mark priceSource,
+	riskModel *risk.Model, orders orderIndex,
+) *Funding {
+	return &Funding{
+		log:     log,
+		cfg:     cfg,
+		manager: manager,
+		index:   index,
+		mark:    mark,
+		risk:    riskModel,
+		orders:  orders,
+	}
+}
+
+// Run settles funding at every interval boundary (00:00, 08:00, 16:00 UTC for 8h) until ctx is done
+func (f *Funding) Run(ctx context.Context) {
+	const op = "funding.Run"
+	log := f.log.With("op", op)
+	if f.cfg.Interval <= 0 {
+		log.Error("funding interval must be positive", "interval", f.cfg.Interval)
+		return
+	}
+
+	for {
+		now := time.Now().UTC()
+		next := now.Truncate(f.cfg.Interval).Add(f.cfg.Interval)
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := f.Settle(ctx, next); err != nil {
+			log.Error("funding settlement failed", "funding_time", next, "error", err)
+		}
+	}
+}
+
+// Settle charges funding of fundingTime to every open position, longs pay shorts when rate is positive
+func (f *Funding) Settle(ctx context.Context, fundingTime time.Time) error {
+	const op = "funding.Settle"
+	log := f.log.With("op", op, "funding_time", fundingTime)
+
+	orders, err := f.manager.GetOpenOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	byTicker := make(map[string][]models.Order)
+	for _, order := range orders {
+		ticker := strings.TrimSpace(order.Ticker)
+		byTicker[ticker] = append(byTicker[ticker], order)
+	}
+
+	var errs []error
+	for ticker, tickerOrders := range byTicker {
+		rate, markPrice, err := f.Rate(ctx, ticker)
+		if err != nil {
+			log.Error("failed to compute funding rate", "ticker", ticker, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", ticker, err))
+			continue
+		}
+
+		payments := make([]models.FundingPayment, 0, len(tickerOrders))
+		for _, order := range tickerOrders {
+			payment := payment(order, ticker, rate, markPrice, fundingTime)
+			if payment.Amount.IsZero() {
+				continue
+			}
+			payments = append(payments, payment)
+		}
+		if len(payments) == 0 {
+			continue
+		}
+
+		applied, repriced, err := f.manager.ApplyFundingPayments(ctx, payments, func(order models.Order) (decimal.Decimal, error) {
+			return f.risk.LiquidationPrice(ticker, risk.PositionOf(order))
+		})
+		if err != nil {
+			log.Error("failed to apply funding payments", "ticker", ticker, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", ticker, err))
+			continue
+		}
+		// funding taken from extra margin moved liquidation price, re-score orders in orders:long:/orders:short:
+		for _, order := range repriced {
+			err := f.orders.SaveOrder(ctx, models.Order{Ticker: ticker, Type: order.Type, Id: order.Id, LiquidationPrice: order.LiquidationPrice})
+			if err != nil {
+				log.Error("failed to save repriced order", "order_id", order.Id, "error", err)
+				errs = append(errs, fmt.Errorf("%s: %w", ticker, err))
+			}
+		}
+		log.Info("funding settled", "ticker", ticker, "rate", rate, "mark_price", markPrice,
+			"payments", applied, "repriced", len(repriced))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %w", op, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Rate returns funding rate of ticker with mark price used to price positions.
+// Configured fixed rate wins, otherwise rate = premium + clamp(interest - premium),
+// where premium = (mark - index) / index
+func (f *Funding) Rate(ctx context.Context, ticker string) (decimal.Decimal, decimal.Decimal, error) {
+	markPrice, err := f.price(ctx, f.mark, ticker)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("mark price: %w", err)
+	}
+
+	if fixed, ok := f.cfg.FixedRates[ticker]; ok {
+		return decimal.NewFromFloat(fixed), markPrice, nil
+	}
+
+	indexPrice, err := f.price(ctx, f.index, ticker)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("index price: %w", err)
+	}
+
+	premium := markPrice.Sub(indexPrice).Div(indexPrice)
+	clamp := decimal.NewFromFloat(f.cfg.PremiumClamp)
+	interest := decimal.NewFromFloat(f.cfg.InterestRate)
+	rate := premium.Add(clampAbs(interest.Sub(premium), clamp))
+	if f.cfg.MaxRate > 0 {
+		rate = clampAbs(rate, decimal.NewFromFloat(f.cfg.MaxRate))
+	}
+
+	return rate.Round(8), markPrice, nil
+}
+
+// GetPayments returns page of user's funding payments, newest first
+func (f *Funding) GetPayments(ctx context.Context, userId int64, limit, offset int) ([]models.FundingPayment, error) {
+	const op = "funding.GetPayments"
+
+	if limit <= 0 {
+		limit = DefaultPaymentsLimit
+	}
+	limit = min(limit, MaxPaymentsLimit)
+	offset = max(offset, 0)
+
+	payments, err := f.manager.GetUserFundingPayments(ctx, userId, limit, offset)
+	if err != nil {
+		f.log.Error("failed to get funding payments", "userId", userId, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return payments, nil
+}
+
+func (f *Funding) price(ctx context.Context, source priceSource, ticker string) (decimal.Decimal, error) {
+	price, err := source.GetPrice(ctx, ticker)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	priceDec, err := decimal.NewFromString(price)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !priceDec.IsPositive() {
+		return decimal.Zero, ErrInvalidPrice
+	}
+	return priceDec, nil
+}
+
+// payment prices funding of position at mark price: long pays notional * rate, short receives it
+func payment(order models.Order, ticker string, rate, markPrice decimal.Decimal, fundingTime time.Time) models.FundingPayment {
+	position := risk.PositionOf(order)
+	notional := position.Size.Mul(markPrice).Div(position.EntryPrice)
+
+	amount := notional.Mul(rate).Round(2)
+	if order.Type == models.Long {
+		amount = amount.Neg()
+	}
+
+	return models.FundingPayment{
+		OrderId:     order.Id,
+		UserId:      order.UserId,
+		Ticker:      ticker,
+		Rate:        rate,
+		MarkPrice:   markPrice,
+		Notional:    notional.Round(2),
+		Amount:      amount,
+		FundingTime: fundingTime,
+	}
+}
+
+func clampAbs(v, limit decimal.Decimal) decimal.Decimal {
+	return decimal.Min(decimal.Max(v, limit.Neg()), limit)
+}
@@ -0,0 +1,55 @@
+package insurance
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	DefaultHistoryLimit = 100
+	MaxHistoryLimit     = 1000
+)
+
+type Insurance struct {
+	log     slog.Logger
+	manager Manager
+}
+
+type Manager interface {
+	GetInsuranceFund(ctx context.Context) (models.InsuranceFund, error)
+	GetInsuranceFundHistory(ctx context.Context, limit, offset int) ([]models.InsuranceFundEntry, error)
+}
+
+func New(log slog.Logger, manager Manager) *Insurance {
+	return &Insurance{
+		log:     log,
+		manager: manager,
+	}
+}
+
+// GetFund returns insurance fund balance with page of its history, newest first
+func (i *Insurance) GetFund(ctx context.Context, limit, offset int) (models.InsuranceFund, []models.InsuranceFundEntry, error) {
+	const op = "insurance.GetFund"
+
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	limit = min(limit, MaxHistoryLimit)
+	offset = max(offset, 0)
+
+	fund, err := i.manager.GetInsuranceFund(ctx)
+	if err != nil {
+		i.log.Error("failed to get insurance fund", "error", err)
+		return models.InsuranceFund{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	history, err := i.manager.GetInsuranceFundHistory(ctx, limit, offset)
+	if err != nil {
+		i.log.Error("failed to get insurance fund history", "error", err)
+		return models.InsuranceFund{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fund, history, nil
+}
@@ -0,0 +1,107 @@
+package ledger
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	DefaultDriftsLimit = 100
+	MaxDriftsLimit     = 1000
+)
+
+type Ledger struct {
+	log      slog.Logger
+	manager  Manager
+	interval time.Duration
+}
+
+type Manager interface {
+	FindLedgerDrifts(ctx context.Context) ([]models.LedgerDrift, error)
+	SaveLedgerDrifts(ctx context.Context, drifts []models.LedgerDrift) error
+	GetLedgerDrifts(ctx context.Context, limit, offset int) ([]models.LedgerDrift, error)
+}
+
+func New(log slog.Logger, manager Manager, reconcileInterval time.Duration) *Ledger {
+	return &Ledger{
+		log:      log,
+		manager:  manager,
+		interval: reconcileInterval,
+	}
+}
+
+// Run reconciles ledger every interval until ctx is done
+func (l *Ledger) Run(ctx context.Context) {
+	const op = "ledger.Run"
+	log := l.log.With("op", op)
+	if l.interval <= 0 {
+		log.Error("reconcile interval must be positive", "interval", l.interval)
+		return
+	}
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := l.Reconcile(ctx); err != nil {
+			log.Error("ledger reconciliation failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile flags drift between users.balance and ledger, and unbalanced journals.
+// Drift which persists is recorded once, it is recorded again only when its values change
+func (l *Ledger) Reconcile(ctx context.Context) ([]models.LedgerDrift, error) {
+	const op = "ledger.Reconcile"
+	log := l.log.With("op", op)
+
+	drifts, err := l.manager.FindLedgerDrifts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(drifts) == 0 {
+		log.Debug("ledger is consistent")
+		return drifts, nil
+	}
+
+	for _, d := range drifts {
+		log.Warn("ledger drift detected",
+			"kind", d.Kind,
+			"user_id", d.UserId,
+			"journal_id", d.JournalId,
+			"expected", d.Expected,
+			"actual", d.Actual)
+	}
+	if err := l.manager.SaveLedgerDrifts(ctx, drifts); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return drifts, nil
+}
+
+// GetDrifts returns page of drifts found by reconciliation, newest first
+func (l *Ledger) GetDrifts(ctx context.Context, limit, offset int) ([]models.LedgerDrift, error) {
+	const op = "ledger.GetDrifts"
+
+	if limit <= 0 {
+		limit = DefaultDriftsLimit
+	}
+	limit = min(limit, MaxDriftsLimit)
+	offset = max(offset, 0)
+
+	drifts, err := l.manager.GetLedgerDrifts(ctx, limit, offset)
+	if err != nil {
+		l.log.Error("failed to get ledger drifts", "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return drifts, nil
+}
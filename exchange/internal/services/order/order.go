@@ -16,6 +16,7 @@ import (
 var (
 	ErrInvalidTicker     = errors.New("ticker is invalid")
 	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrForeignOrder      = errors.New("order belongs to another user")
 )
 
 type Order struct {
@@ -38,6 +39,7 @@ type Manager interface {
 		createdAt time.Time, liquidationPrice decimal.Decimal, ticker string) (uuid.UUID, error)
 	GetOrder(ctx context.Context, id uuid.UUID) (models.Order, error)
 	GetUserOrders(ctx context.Context, userId int64) ([]models.Order, error)
+	GetUserActiveOrders(ctx context.Context, userId int64) ([]models.Order, error)
 	OpenOrder(
 		ctx context.Context,
 		id uuid.UUID,
@@ -50,15 +52,54 @@ type Manager interface {
 		status models.OrderStatus,
 		createdAt time.Time, liquidationPrice decimal.Decimal,
 		ticekr string,
+		limitPrice *decimal.Decimal,
+		stops models.Stops,
+		openFee models.OrderFee,
 	) (orderID uuid.UUID, err error)
+	UpdateOrderStops(ctx context.Context, orderID uuid.UUID, stops models.Stops) (uuid.UUID, error)
+	SetTrailingStop(ctx context.Context, orderID uuid.UUID, ts *models.TrailingStop) (uuid.UUID, error)
+	UpdateTrailingExtreme(ctx context.Context, orderID uuid.UUID, extreme decimal.Decimal) error
+	GetOpenTrailingOrders(ctx context.Context) ([]models.Order, error)
+	PartialCloseOrder(
+		ctx context.Context,
+		orderID uuid.UUID,
+		expectedMargin decimal.Decimal,
+		expectedExtraMargin decimal.Decimal,
+		closedMargin decimal.Decimal,
+		closedExtraMargin decimal.Decimal,
+		closePrice decimal.Decimal,
+		realizedPnl decimal.Decimal,
+		closeFee models.OrderFee,
+		fundEntries []models.InsuranceFundEntry,
+		liquidationPrice decimal.Decimal,
+	) (decimal.Decimal, error)
+	AdjustMargin(
+		ctx context.Context,
+		orderID uuid.UUID,
+		expectedExtraMargin decimal.Decimal,
+		delta decimal.Decimal,
+		liquidationPrice decimal.Decimal,
+	) (decimal.Decimal, error)
+	GetOrderCloses(ctx context.Context, orderID uuid.UUID) ([]models.OrderClose, error)
+	GetOrderFees(ctx context.Context, orderID uuid.UUID) ([]models.OrderFee, error)
+	GetUserVolume(ctx context.Context, userId int64, since time.Time) (decimal.Decimal, error)
+	StreamUserOrders(ctx context.Context, userId int64, filter models.OrderExportFilter, fn func(models.OrderExport) error) error
+	FillOrder(ctx context.Context, orderID uuid.UUID, entryPrice decimal.Decimal, liquidationPrice decimal.Decimal) (uuid.UUID, error)
+	CancelOrder(ctx context.Context, orderID uuid.UUID) (uuid.UUID, error)
 	CloseOrder(
 		ctx context.Context,
 		orderID uuid.UUID,
 		closePrice decimal.Decimal,
 		balanceIncrease decimal.Decimal,
+		closeFee models.OrderFee,
+		fundEntries []models.InsuranceFundEntry,
 	) (orderId uuid.UUID, err error)
 	GetLiqOrders(ctx context.Context, markPrice decimal.Decimal, pairId int64) ([]uuid.UUID, error)
-	LiquidateOrder(ctx context.Context, orderID uuid.UUID, closePrice decimal.Decimal) (uuid.UUID, error)
+	LiquidateOrder(ctx context.Context,
+		orderID uuid.UUID,
+		closePrice decimal.Decimal,
+		liquidationFee decimal.Decimal,
+		fundEntries []models.InsuranceFundEntry) (uuid.UUID, error)
 }
 
 type TradingPairManager interface {
@@ -121,65 +162,135 @@ func (o *Order) OpenOrder(ctx context.Context,
 	orderType models.OrderType,
 	margin decimal.Decimal,
 	leverage uint8,
-	entryPrice decimal.Decimal, liquidationPrice decimal.Decimal) (uuid.UUID, error) {
-	const op = "order.CreateOrder"
+	entryPrice decimal.Decimal, liquidationPrice decimal.Decimal,
+	stops models.Stops,
+	openFee models.OrderFee) (uuid.UUID, error) {
+	const op = "order.OpenOrder"
+
+	orderId, err := o.openOrder(ctx, userId, ticker, orderType, margin, leverage, entryPrice, liquidationPrice, models.Open, nil, stops, openFee)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orderId, nil
+}
 
+// PlaceLimitOrder creates pending order and reserves its margin and fee until it is filled or canceled
+func (o *Order) PlaceLimitOrder(ctx context.Context,
+	userId int64,
+	ticker string,
+	orderType models.OrderType,
+	margin decimal.Decimal,
+	leverage uint8,
+	limitPrice decimal.Decimal, liquidationPrice decimal.Decimal,
+	stops models.Stops,
+	openFee models.OrderFee) (uuid.UUID, error) {
+	const op = "order.PlaceLimitOrder"
+
+	orderId, err := o.openOrder(ctx, userId, ticker, orderType, margin, leverage, limitPrice, liquidationPrice, models.Pending, &limitPrice, stops, openFee)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orderId, nil
+}
+
+func (o *Order) openOrder(ctx context.Context,
+	userId int64,
+	ticker string,
+	orderType models.OrderType,
+	margin decimal.Decimal,
+	leverage uint8,
+	entryPrice decimal.Decimal, liquidationPrice decimal.Decimal,
+	orderStatus models.OrderStatus, limitPrice *decimal.Decimal,
+	stops models.Stops,
+	openFee models.OrderFee) (uuid.UUID, error) {
 	baseAsset, quoteAsset, err := checkTicker(ticker)
 	if err != nil {
 		o.log.Error("Invalid ticker", "ticker", ticker, "err", err)
-		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		return uuid.Nil, err
 	}
 	pairId, err := o.tp.GetTradingPairId(baseAsset, quoteAsset)
 	if err != nil {
 		o.log.Error("failed to get trading pair id", "error", err)
-		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		return uuid.Nil, err
 	}
 
 	//check if user exists
 	currUser, err := o.um.GetUserById(ctx, userId)
 	if err != nil {
 		o.log.Error("failed to get user", "userId", userId, "err", err)
-		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		return uuid.Nil, err
 	}
 
-	//check if user has enough funds to open order
-	if currUser.Balance.LessThan(margin) {
+	//check if user has enough funds to open order and pay fee
+	if currUser.Balance.LessThan(margin.Add(openFee.Amount)) {
 		o.log.Info("insufficient balance for order", "userId", userId, "balance", currUser.Balance)
-		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrInsufficientFunds)
+		return uuid.Nil, ErrInsufficientFunds
 	}
 
 	orderId := uuid.New()
-	orderStatus := models.Open
 	createdAt := time.Now()
 
-	orderId, err = o.Manager.OpenOrder(ctx, orderId, userId, pairId, orderType, margin, leverage, entryPrice, orderStatus, createdAt, liquidationPrice, ticker)
+	orderId, err = o.Manager.OpenOrder(ctx, orderId, userId, pairId, orderType, margin, leverage, entryPrice, orderStatus, createdAt, liquidationPrice, ticker, limitPrice, stops, openFee)
 	if err != nil {
 		o.log.Error("failed to create order", "error", err)
+		return uuid.Nil, err
+	}
+
+	return orderId, nil
+}
+
+// FillOrder opens position of pending limit order at fill price
+func (o *Order) FillOrder(ctx context.Context,
+	orderID uuid.UUID,
+	entryPrice decimal.Decimal,
+	liquidationPrice decimal.Decimal) (uuid.UUID, error) {
+	const op = "order.FillOrder"
+
+	orderId, err := o.Manager.FillOrder(ctx, orderID, entryPrice, liquidationPrice)
+	if err != nil {
+		o.log.Error("failed to fill order", "order", orderID, "error", err)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return orderId, nil
 }
 
+// CancelOrder cancels pending order of user and returns reserved margin
+func (o *Order) CancelOrder(ctx context.Context, userId int64, orderID uuid.UUID) (uuid.UUID, error) {
+	const op = "order.CancelOrder"
+
+	if _, err := o.GetUserOrder(ctx, userId, orderID); err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orderId, err := o.Manager.CancelOrder(ctx, orderID)
+	if err != nil {
+		o.log.Error("failed to cancel order", "order", orderID, "error", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orderId, nil
+}
+
+// CloseOrder closes user's position, loss past its margin is settled with insurance fund by fundEntries
 func (o *Order) CloseOrder(ctx context.Context,
+	userId int64,
 	orderID uuid.UUID,
 	closePrice decimal.Decimal,
-	balanceIncrease decimal.Decimal) (uuid.UUID, error) {
+	balanceIncrease decimal.Decimal,
+	closeFee models.OrderFee,
+	fundEntries []models.InsuranceFundEntry) (uuid.UUID, error) {
 	const op = "order.CloseOrder"
 
-	// check if order exists
-	_, err := o.Manager.GetOrder(ctx, orderID)
+	// check if order exists and belongs to caller
+	_, err := o.GetUserOrder(ctx, userId, orderID)
 	if err != nil {
-		o.log.Error("failed to get order", "order", orderID, "error", err)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// TODO: check that order owner equals method caller
-	// 2 ways:
-	// 1) liquidation calls CloseOrder() and sets order status
-	// 2) liquidation calls its own method
-
-	orderId, err := o.Manager.CloseOrder(ctx, orderID, closePrice, balanceIncrease)
+	orderId, err := o.Manager.CloseOrder(ctx, orderID, closePrice, balanceIncrease, closeFee, fundEntries)
 	if err != nil {
 		o.log.Error("failed to close order", "error", err)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
@@ -188,6 +299,107 @@ func (o *Order) CloseOrder(ctx context.Context,
 	return orderId, nil
 }
 
+// UpdateOrderStops changes stop-loss and take-profit of user's order
+func (o *Order) UpdateOrderStops(ctx context.Context, userId int64, orderID uuid.UUID, stops models.Stops) (uuid.UUID, error) {
+	const op = "order.UpdateOrderStops"
+
+	if _, err := o.GetUserOrder(ctx, userId, orderID); err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orderId, err := o.Manager.UpdateOrderStops(ctx, orderID, stops)
+	if err != nil {
+		o.log.Error("failed to update order stops", "order", orderID, "error", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orderId, nil
+}
+
+// SetTrailingStop attaches trailing stop to user's open order, nil removes it
+func (o *Order) SetTrailingStop(ctx context.Context, userId int64, orderID uuid.UUID, ts *models.TrailingStop) (uuid.UUID, error) {
+	const op = "order.SetTrailingStop"
+
+	if _, err := o.GetUserOrder(ctx, userId, orderID); err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orderId, err := o.Manager.SetTrailingStop(ctx, orderID, ts)
+	if err != nil {
+		o.log.Error("failed to set trailing stop", "order", orderID, "error", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orderId, nil
+}
+
+// PartialCloseOrder closes part of user's position and sets liquidation price of the rest,
+// loss past released margin is settled with insurance fund by fundEntries. Returns remaining margin
+func (o *Order) PartialCloseOrder(ctx context.Context,
+	userId int64,
+	order models.Order,
+	closedMargin decimal.Decimal,
+	closedExtraMargin decimal.Decimal,
+	closePrice decimal.Decimal,
+	realizedPnl decimal.Decimal,
+	closeFee models.OrderFee,
+	fundEntries []models.InsuranceFundEntry,
+	liquidationPrice decimal.Decimal) (decimal.Decimal, error) {
+	const op = "order.PartialCloseOrder"
+
+	if order.UserId != userId {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrForeignOrder)
+	}
+
+	remaining, err := o.Manager.PartialCloseOrder(ctx, order.Id, order.Margin, order.ExtraMargin,
+		closedMargin, closedExtraMargin, closePrice, realizedPnl, closeFee, fundEntries, liquidationPrice)
+	if err != nil {
+		o.log.Error("failed to partially close order", "order", order.Id, "error", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return remaining, nil
+}
+
+// AdjustMargin adds (positive delta) or withdraws (negative delta) isolated margin of user's order
+func (o *Order) AdjustMargin(ctx context.Context,
+	userId int64,
+	order models.Order,
+	delta decimal.Decimal,
+	liquidationPrice decimal.Decimal) (decimal.Decimal, error) {
+	const op = "order.AdjustMargin"
+
+	if order.UserId != userId {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrForeignOrder)
+	}
+
+	extraMargin, err := o.Manager.AdjustMargin(ctx, order.Id, order.ExtraMargin, delta, liquidationPrice)
+	if err != nil {
+		o.log.Error("failed to adjust margin", "order", order.Id, "error", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return extraMargin, nil
+}
+
+// GetUserOrder returns order only if it belongs to user
+func (o *Order) GetUserOrder(ctx context.Context, userId int64, orderID uuid.UUID) (models.Order, error) {
+	const op = "order.GetUserOrder"
+
+	order, err := o.Manager.GetOrder(ctx, orderID)
+	if err != nil {
+		o.log.Error("failed to get order", "order", orderID, "error", err)
+		return models.Order{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if order.UserId != userId {
+		o.log.Warn("access to foreign order", "order", orderID, "userId", userId)
+		return models.Order{}, fmt.Errorf("%s: %w", op, ErrForeignOrder)
+	}
+
+	return order, nil
+}
+
 func (o *Order) GetOrder(ctx context.Context, orderID uuid.UUID) (models.Order, error) {
 	const op = "order.GetOrder"
 	order, err := o.Manager.GetOrder(ctx, orderID)
@@ -198,9 +410,26 @@ func (o *Order) GetOrder(ctx context.Context, orderID uuid.UUID) (models.Order,
 	return order, nil
 }
 
-func (o *Order) LiquidateOrder(ctx context.Context, orderID uuid.UUID, closePrice decimal.Decimal) (uuid.UUID, error) {
+// GetUserBalance returns wallet balance of user, margin of active orders is already taken from it
+func (o *Order) GetUserBalance(ctx context.Context, userId int64) (decimal.Decimal, error) {
+	const op = "order.GetUserBalance"
+
+	currUser, err := o.um.GetUserById(ctx, userId)
+	if err != nil {
+		o.log.Error("failed to get user", "userId", userId, "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return currUser.Balance, nil
+}
+
+func (o *Order) LiquidateOrder(ctx context.Context,
+	orderID uuid.UUID,
+	closePrice decimal.Decimal,
+	liquidationFee decimal.Decimal,
+	fundEntries []models.InsuranceFundEntry) (uuid.UUID, error) {
 	const op = "order.LiquidateOrder"
-	orderId, err := o.Manager.LiquidateOrder(ctx, orderID, closePrice)
+	orderId, err := o.Manager.LiquidateOrder(ctx, orderID, closePrice, liquidationFee, fundEntries)
 	if err != nil {
 		o.log.Error("failed to liquidate order", "order", orderID, "error", err)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
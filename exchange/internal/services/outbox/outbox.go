@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Relay publishes domain events written to outbox by storage transactions.
+// Delivery is at least once: message published right before crash is published again,
+// with the same message id, so JetStream drops the duplicate
+type Relay struct {
+	log       slog.Logger
+	cfg       config.OutboxConfig
+	storage   Storage
+	publisher Publisher
+}
+
+type Storage interface {
+	RelayOutbox(ctx context.Context, limit int, publish func(models.OutboxMessage) error) (int, error)
+}
+
+type Publisher interface {
+	PublishMsg(ctx context.Context, subject, msgId string, data []byte) error
+}
+
+func New(log slog.Logger, cfg config.OutboxConfig, storage Storage, publisher Publisher) *Relay {
+	return &Relay{
+		log:       log,
+		cfg:       cfg,
+		storage:   storage,
+		publisher: publisher,
+	}
+}
+
+// Run relays outbox every interval until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	const op = "outbox.Run"
+	log := r.log.With("op", op)
+	if r.cfg.Interval <= 0 || r.cfg.BatchSize <= 0 {
+		log.Error("interval and batch size must be positive", "interval", r.cfg.Interval, "batch_size", r.cfg.BatchSize)
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Relay(ctx); err != nil {
+			log.Error("outbox relay failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes batches of outbox messages until outbox is drained
+// or other relay holds it. Returns number of published messages
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	const op = "outbox.Relay"
+
+	total := 0
+	for {
+		published, err := r.storage.RelayOutbox(ctx, r.cfg.BatchSize, func(m models.OutboxMessage) error {
+			return r.publisher.PublishMsg(ctx, m.Subject, m.EventId.String(), m.Payload)
+		})
+		total += published
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", op, err)
+		}
+		if published < r.cfg.BatchSize || ctx.Err() != nil {
+			return total, nil
+		}
+	}
+}
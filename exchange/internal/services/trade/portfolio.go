@@ -0,0 +1,200 @@
+package trade
+
+import (
+	"Exchange/internal/domain/models"
+	"Exchange/internal/risk"
+	"Exchange/internal/storage/redis"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// GetPortfolio values user's open positions at current prices and sums account totals.
+// Pending limit orders have no pnl yet, only their reserved margin counts as used.
+// Position of ticker without price is returned unpriced and adds no pnl
+func (t *Trade) GetPortfolio(ctx context.Context, userId int64) (models.Portfolio, error) {
+	const op = "Trade.GetPortfolio"
+
+	balance, err := t.orderService.GetUserBalance(ctx, userId)
+	if err != nil {
+		return models.Portfolio{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orders, err := t.orderService.Manager.GetUserActiveOrders(ctx, userId)
+	if err != nil {
+		t.log.Error("Error getting active orders", "error", err, "userId", userId)
+		return models.Portfolio{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	portfolio := models.Portfolio{
+		Balance:          balance,
+		AvailableBalance: balance,
+		Positions:        []models.PortfolioPosition{},
+	}
+	prices := make(map[string]tickerPrices)
+	for _, order := range orders {
+		portfolio.UsedMargin = portfolio.UsedMargin.Add(order.Margin).Add(order.ExtraMargin)
+		if order.Status != models.Open {
+			continue
+		}
+
+		ticker := strings.TrimSpace(order.Ticker)
+		price, ok := prices[ticker]
+		if !ok {
+			price, err = t.tickerPrices(ctx, ticker)
+			if err != nil {
+				return models.Portfolio{}, fmt.Errorf("%s: %w", op, err)
+			}
+			prices[ticker] = price
+		}
+
+		position := t.valuePosition(ticker, order, price)
+		portfolio.UnrealizedPnl = portfolio.UnrealizedPnl.Add(position.UnrealizedPnl)
+		portfolio.Positions = append(portfolio.Positions, position)
+	}
+	portfolio.Equity = balance.Add(portfolio.UsedMargin).Add(portfolio.UnrealizedPnl)
+
+	return portfolio, nil
+}
+
+// tickerPrices are prices position is valued at, unpriced ticker has none yet
+type tickerPrices struct {
+	last     decimal.Decimal
+	mark     decimal.Decimal
+	unpriced bool
+}
+
+// tickerPrices returns last and mark price of ticker, missing price makes ticker unpriced
+func (t *Trade) tickerPrices(ctx context.Context, ticker string) (tickerPrices, error) {
+	last, err := t.currentPrice(ctx, ticker)
+	if errors.Is(err, redis.ErrPriceNotFound) {
+		return tickerPrices{unpriced: true}, nil
+	}
+	if err != nil {
+		return tickerPrices{}, err
+	}
+	mark, err := t.markPrice(ctx, ticker)
+	if err != nil {
+		return tickerPrices{}, err
+	}
+
+	return tickerPrices{last: last, mark: mark}, nil
+}
+
+// valuePosition computes pnl and roe of open order at last price,
+// distance to liquidation and margin ratio at mark price, as liquidation is triggered by mark
+func (t *Trade) valuePosition(ticker string, order models.Order, prices tickerPrices) models.PortfolioPosition {
+	p := risk.PositionOf(order)
+	position := models.PortfolioPosition{
+		OrderId:          order.Id,
+		Ticker:           ticker,
+		Type:             order.Type,
+		Leverage:         order.Leverage,
+		Margin:           p.Margin,
+		Size:             p.Size,
+		EntryPrice:       order.EntryPrice,
+		LiquidationPrice: order.LiquidationPrice,
+		Unpriced:         prices.unpriced,
+	}
+	if prices.unpriced {
+		return position
+	}
+
+	pnl := calculateOrderProfit(order, prices.last).Round(2)
+	position.CurrentPrice = prices.last
+	position.MarkPrice = prices.mark
+	position.UnrealizedPnl = pnl
+	if p.Margin.IsPositive() {
+		position.Roe = pnl.Div(p.Margin).Mul(hundred).Round(2)
+	}
+	if prices.mark.IsPositive() {
+		position.LiquidationDistance = prices.mark.Sub(order.LiquidationPrice).Abs().Div(prices.mark).Mul(hundred).Round(2)
+	}
+
+	// position with no equity left is past liquidation, ratio is capped there
+	position.MarginRatio = hundred
+	if equity := p.Margin.Add(calculateOrderProfit(order, prices.mark)); equity.IsPositive() {
+		maintenance := t.riskModel.MaintenanceMargin(ticker, p.Size)
+		position.MarginRatio = decimal.Min(maintenance.Div(equity).Mul(hundred), hundred).Round(2)
+	}
+
+	return position
+}
+
+func (t *Trade) currentPrice(ctx context.Context, ticker string) (decimal.Decimal, error) {
+	price, err := t.redis.GetPrice(ctx, ticker)
+	if err != nil {
+		t.log.Error("Error getting price", "error", err, "ticker", ticker)
+		return decimal.Zero, err
+	}
+	priceDec, err := decimal.NewFromString(price)
+	if err != nil {
+		t.log.Error("Error converting price", "error", err, "price", price)
+		return decimal.Zero, err
+	}
+
+	return priceDec, nil
+}
+
+// markPrice returns price liquidations and stops are triggered by,
+// last price stands in for mark until mark price service has published it
+func (t *Trade) markPrice(ctx context.Context, ticker string) (decimal.Decimal, error) {
+	price, err := t.redis.MarkPrices().GetPrice(ctx, ticker)
+	if err != nil {
+		t.log.Warn("No mark price, using last price", "error", err, "ticker", ticker)
+		return t.currentPrice(ctx, ticker)
+	}
+	priceDec, err := decimal.NewFromString(price)
+	if err != nil {
+		t.log.Error("Error converting price", "error", err, "price", price)
+		return decimal.Zero, err
+	}
+
+	return priceDec, nil
+}
+
+// lastPrice returns price deals are executed at, refusing price which stopped updating
+func (t *Trade) lastPrice(ctx context.Context, ticker string) (decimal.Decimal, error) {
+	quote, err := t.redis.GetQuote(ctx, ticker)
+	if err != nil {
+		t.log.Error("Error getting price", "error", err, "ticker", ticker)
+		return decimal.Zero, err
+	}
+	if err := t.guard.Check(ticker, quote, time.Now()); err != nil {
+		t.log.Warn("Stale price", "error", err, "ticker", ticker)
+		return decimal.Zero, err
+	}
+	priceDec, err := decimal.NewFromString(quote.Price)
+	if err != nil {
+		t.log.Error("Error converting price", "error", err, "price", quote.Price)
+		return decimal.Zero, err
+	}
+
+	return priceDec, nil
+}
+
+// freshMarkPrice returns mark price liquidations are executed at, refusing mark price
+// which stopped updating. Fresh last price stands in for missing mark price
+func (t *Trade) freshMarkPrice(ctx context.Context, ticker string) (decimal.Decimal, error) {
+	quote, err := t.redis.MarkPrices().GetQuote(ctx, ticker)
+	if err != nil {
+		t.log.Warn("No mark price, using last price", "error", err, "ticker", ticker)
+		return t.lastPrice(ctx, ticker)
+	}
+	if err := t.guard.Check(ticker, quote, time.Now()); err != nil {
+		t.log.Warn("Stale mark price", "error", err, "ticker", ticker)
+		return decimal.Zero, err
+	}
+	priceDec, err := decimal.NewFromString(quote.Price)
+	if err != nil {
+		t.log.Error("Error converting price", "error", err, "price", quote.Price)
+		return decimal.Zero, err
+	}
+
+	return priceDec, nil
+}
@@ -2,6 +2,9 @@ package trade
 
 import (
 	"Exchange/internal/domain/models"
+	"Exchange/internal/fees"
+	"Exchange/internal/marketdata"
+	"Exchange/internal/risk"
 	"Exchange/internal/services/order"
 	"Exchange/internal/storage/postgres"
 	"Exchange/internal/storage/redis"
@@ -12,18 +15,31 @@ import (
 	"github.com/shopspring/decimal"
 	"log/slog"
 	"strings"
+	"time"
 )
 
 var (
 	ErrNegativeMargin     = errors.New("margin can't be negative")
 	ErrNegativeEntryPrice = errors.New("entry price can't be negative")
 	ErrInvalidLeverage    = errors.New("invalid leverage")
+	ErrInvalidLimitPrice  = errors.New("invalid limit price")
+	ErrInvalidStops       = errors.New("invalid stop-loss or take-profit")
+	ErrInvalidTrailing    = errors.New("invalid trailing stop")
+	ErrInvalidCloseAmount = errors.New("invalid partial close amount")
+	ErrInvalidMargin      = errors.New("invalid margin amount")
+	ErrWithdrawLiquidates = errors.New("margin withdrawal would liquidate position")
+	ErrInvalidPeriod      = errors.New("invalid period")
+	ErrLimitNotReached    = errors.New("current price doesn't reach limit price")
+	ErrNotLiquidatable    = errors.New("current mark price doesn't reach liquidation price")
 )
 
 type Trade struct {
 	log          slog.Logger
 	orderService order.Order
 	redis        redis.Redis
+	riskModel    *risk.Model
+	fees         *fees.Schedule
+	guard        *marketdata.Guard
 }
 
 func (t *Trade) GetUserOrders(ctx context.Context, id int64) ([]models.Order, error) {
@@ -39,11 +55,31 @@ func (t *Trade) GetUserOrders(ctx context.Context, id int64) ([]models.Order, er
 	return orders, nil
 }
 
-func New(log *slog.Logger, orderService order.Order, redis redis.Redis) *Trade {
+// GetUserOrder returns order of user, foreign orders are rejected
+func (t *Trade) GetUserOrder(ctx context.Context, userId int64, orderId uuid.UUID) (models.Order, error) {
+	const op = "Trade.GetUserOrder"
+
+	order, err := t.orderService.GetUserOrder(ctx, userId, orderId)
+	if err != nil {
+		return models.Order{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return order, nil
+}
+
+func New(log *slog.Logger,
+	orderService order.Order,
+	redis redis.Redis,
+	riskModel *risk.Model,
+	feeSchedule *fees.Schedule,
+	guard *marketdata.Guard) *Trade {
 	return &Trade{
 		log:          *log,
 		orderService: orderService,
 		redis:        redis,
+		riskModel:    riskModel,
+		fees:         feeSchedule,
+		guard:        guard,
 	}
 }
 
@@ -52,7 +88,8 @@ func (t *Trade) OpenTradeDeal(ctx context.Context,
 	ticker string,
 	orderType models.OrderType,
 	margin decimal.Decimal,
-	leverage uint8) (uuid.UUID, error) {
+	leverage uint8,
+	stops models.Stops) (uuid.UUID, error) {
 	const op = "Trade.OpenTradeDeal"
 
 	if margin.LessThanOrEqual(decimal.Zero) {
@@ -61,49 +98,194 @@ func (t *Trade) OpenTradeDeal(ctx context.Context,
 	if leverage <= 0 {
 		return uuid.Nil, ErrInvalidLeverage
 	}
+	if err := t.riskModel.CheckLeverage(ticker, margin.Mul(decimal.NewFromInt(int64(leverage))), leverage); err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrInvalidLeverage, err)
+	}
 
-	entryPrice, err := t.redis.GetPrice(ctx, ticker)
-	t.log.Info("OpenTradeDeal: ticker:", ticker)
+	t.log.Info("OpenTradeDeal", "ticker", ticker)
+	entryPriceDec, err := t.lastPrice(ctx, ticker)
 	if err != nil {
-		t.log.Error("Error getting entryPrice", "error", err, "ticker", ticker)
 		return uuid.Nil, fmt.Errorf("failed to get entry price. %s: %w", op, err)
 	}
-	entryPriceDec, err := decimal.NewFromString(entryPrice)
+
+	if err := validateStops(orderType, entryPriceDec, stops); err != nil {
+		return uuid.Nil, err
+	}
+
+	liqPrice, err := t.liquidationPrice(ticker, models.Order{Type: orderType, EntryPrice: entryPriceDec,
+		Margin: margin, Leverage: leverage})
 	if err != nil {
-		t.log.Error("Error converting entryPrice", "error", err, "entryPrice", entryPrice)
-		return uuid.Nil, fmt.Errorf("failed to convert entryPrice. %s: %w", op, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// todo: liquidation price
-	lev := decimal.NewFromInt(int64(leverage))
-	var liqPrice decimal.Decimal
-	if orderType == models.Long {
-		// long: entryPrice * (leverage-1)/leverage
-		liqPrice = entryPriceDec.Mul(lev.Sub(decimal.NewFromInt(1))).Div(lev)
-	} else {
-		// short: entryPrice * (leverage+1)/leverage
-		liqPrice = entryPriceDec.Mul(lev.Add(decimal.NewFromInt(1))).Div(lev)
+	openFee, err := t.tradeFee(ctx, userId, ticker, models.OpenFee, models.Taker, positionNotional(margin, leverage))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	id, err := t.orderService.OpenOrder(ctx, userId, ticker, orderType, margin, leverage, entryPriceDec, liqPrice)
+	id, err := t.orderService.OpenOrder(ctx, userId, ticker, orderType, margin, leverage, entryPriceDec, liqPrice, stops, openFee)
 	if err != nil {
 		t.log.Error("Error opening order", "error", err, "userId", userId)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = t.redis.SaveOrder(ctx, models.Order{Ticker: ticker, Type: orderType, Id: id, LiquidationPrice: liqPrice})
+	redisOrder := models.Order{Ticker: ticker, Type: orderType, Id: id, LiquidationPrice: liqPrice,
+		StopLoss: stops.StopLoss, TakeProfit: stops.TakeProfit}
+	err = t.redis.SaveOrder(ctx, redisOrder)
 	if err != nil {
 		t.log.Error("Error saving order to redis", "error", err, "orderId", id)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
+	if err := t.redis.SaveOrderStops(ctx, redisOrder); err != nil {
+		t.log.Error("Error saving order stops to redis", "error", err, "orderId", id)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	return id, nil
 }
 
-func (t *Trade) CloseTradeDeal(ctx context.Context, orderId uuid.UUID, ticker string) (uuid.UUID, error) {
-	const op = "Trade.CloseTradeDeal"
+// PlaceLimitOrder reserves margin and waits until price reaches limitPrice.
+// Limit already reached by last price fills at once and pays taker fee
+func (t *Trade) PlaceLimitOrder(ctx context.Context,
+	userId int64,
+	ticker string,
+	orderType models.OrderType,
+	margin decimal.Decimal,
+	leverage uint8,
+	limitPrice decimal.Decimal,
+	stops models.Stops) (uuid.UUID, error) {
+	const op = "Trade.PlaceLimitOrder"
+
+	if margin.LessThanOrEqual(decimal.Zero) {
+		return uuid.Nil, ErrNegativeMargin
+	}
+	if leverage <= 0 {
+		return uuid.Nil, ErrInvalidLeverage
+	}
+	if err := t.riskModel.CheckLeverage(ticker, margin.Mul(decimal.NewFromInt(int64(leverage))), leverage); err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrInvalidLeverage, err)
+	}
+	if limitPrice.LessThanOrEqual(decimal.Zero) {
+		return uuid.Nil, ErrInvalidLimitPrice
+	}
+
+	if err := validateStops(orderType, limitPrice, stops); err != nil {
+		return uuid.Nil, err
+	}
+
+	liqPrice, err := t.liquidationPrice(ticker, models.Order{Type: orderType, EntryPrice: limitPrice,
+		Margin: margin, Leverage: leverage})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lastPrice, err := t.lastPrice(ctx, ticker)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	liquidity := models.Maker
+	if priceReached(orderType, limitPrice, lastPrice) {
+		liquidity = models.Taker
+	}
+
+	// fee is reserved with margin and refunded if order is canceled
+	openFee, err := t.tradeFee(ctx, userId, ticker, models.OpenFee, liquidity, positionNotional(margin, leverage))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := t.orderService.PlaceLimitOrder(ctx, userId, ticker, orderType, margin, leverage, limitPrice, liqPrice, stops, openFee)
+	if err != nil {
+		t.log.Error("Error placing limit order", "error", err, "userId", userId)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = t.redis.SavePendingOrder(ctx, models.Order{Ticker: ticker, Type: orderType, Id: id, LimitPrice: &limitPrice})
+	if err != nil {
+		t.log.Error("Error saving pending order to redis", "error", err, "orderId", id)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// CancelLimitOrder cancels pending order of user
+func (t *Trade) CancelLimitOrder(ctx context.Context, userId int64, orderId uuid.UUID) (uuid.UUID, error) {
+	const op = "Trade.CancelLimitOrder"
+
+	order, err := t.orderService.GetUserOrder(ctx, userId, orderId)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := t.orderService.CancelOrder(ctx, userId, orderId)
+	if err != nil {
+		t.log.Error("Error canceling order", "error", err, "orderId", orderId)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.redis.RemovePendingOrder(ctx, id.String(), strings.TrimSpace(order.Ticker), order.Type)
+
+	return id, nil
+}
+
+// FillLimitOrder opens pending order triggered by triggerPrice at current price and moves
+// it to liquidation index. Trigger may be replayed long after it was published, so order
+// is filled only if current price still reaches its limit
+func (t *Trade) FillLimitOrder(ctx context.Context, orderId uuid.UUID, triggerPrice decimal.Decimal) (uuid.UUID, error) {
+	const op = "Trade.FillLimitOrder"
 
 	order, err := t.orderService.GetOrder(ctx, orderId)
+	if err != nil {
+		t.log.Error("Error getting order", "error", err, "orderId", orderId)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ticker := strings.TrimSpace(order.Ticker)
+	price, err := t.lastPrice(ctx, ticker)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if order.LimitPrice == nil || !priceReached(order.Type, *order.LimitPrice, price) {
+		t.log.Info("Limit is not reached at current price", "orderId", orderId,
+			"triggerPrice", triggerPrice, "price", price)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrLimitNotReached)
+	}
+
+	position := order
+	position.EntryPrice = price
+	liqPrice, err := t.liquidationPrice(ticker, position)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := t.orderService.FillOrder(ctx, orderId, price, liqPrice)
+	if err != nil {
+		if errors.Is(err, postgres.ErrOrderNotPending) {
+			t.redis.RemovePendingOrder(ctx, orderId.String(), ticker, order.Type)
+		}
+		t.log.Error("Error filling order", "error", err, "orderId", orderId)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.redis.RemovePendingOrder(ctx, id.String(), ticker, order.Type)
+	redisOrder := models.Order{Ticker: ticker, Type: order.Type, Id: id, LiquidationPrice: liqPrice,
+		StopLoss: order.StopLoss, TakeProfit: order.TakeProfit}
+	err = t.redis.SaveOrder(ctx, redisOrder)
+	if err != nil {
+		t.log.Error("Error saving order to redis", "error", err, "orderId", id)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := t.redis.SaveOrderStops(ctx, redisOrder); err != nil {
+		t.log.Error("Error saving order stops to redis", "error", err, "orderId", id)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (t *Trade) CloseTradeDeal(ctx context.Context, userId int64, orderId uuid.UUID) (uuid.UUID, error) {
+	const op = "Trade.CloseTradeDeal"
+
+	order, err := t.orderService.GetUserOrder(ctx, userId, orderId)
 	if err != nil {
 		if errors.Is(err, postgres.ErrOrderNotExists) {
 			t.log.Error("Order not exists", "orderId", orderId)
@@ -112,16 +294,11 @@ func (t *Trade) CloseTradeDeal(ctx context.Context, orderId uuid.UUID, ticker st
 		t.log.Error("Error getting order", "error", err, "orderId", orderId)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
+	ticker := strings.TrimSpace(order.Ticker)
 
 	// getting closePrice
-	closePrice, err := t.redis.GetPrice(ctx, ticker)
-	if err != nil {
-		t.log.Error("Error getting closePrice", "error", err, "ticker", ticker)
-		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
-	}
-	closePriceDec, err := decimal.NewFromString(closePrice)
+	closePriceDec, err := t.lastPrice(ctx, ticker)
 	if err != nil {
-		t.log.Error("Error converting closePrice", "error", err, "closePrice", closePrice)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -129,34 +306,552 @@ func (t *Trade) CloseTradeDeal(ctx context.Context, orderId uuid.UUID, ticker st
 	// -> ((closeP-entryP)/entry)*leverage*margin+margin
 
 	orderProfit := calculateOrderProfit(order, closePriceDec)
-	balanceInc := order.Margin.Add(orderProfit)
 
-	id, err := t.orderService.CloseOrder(ctx, orderId, closePriceDec, balanceInc)
+	closeFee, err := t.tradeFee(ctx, userId, ticker, models.CloseFee, models.Taker, closeNotional(order, closePriceDec))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// last price can be past bankruptcy price while lagging mark hasn't liquidated position yet
+	balanceInc, fundEntries := settleClose(order.Margin.Add(order.ExtraMargin).Add(orderProfit), &closeFee)
+
+	id, err := t.orderService.CloseOrder(ctx, userId, orderId, closePriceDec, balanceInc, closeFee, fundEntries)
 	if err != nil {
 		t.log.Error("Error closing order", "error", err, "orderId", orderId)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	t.redis.RemoveOrder(ctx, id.String(), ticker, order.Type)
+	t.removeOrderIndexes(ctx, id, ticker, order.Type)
+	if len(fundEntries) > 0 {
+		t.log.Warn("position closed past bankruptcy price", "orderId", id, "loss", fundEntries[0].Amount.Neg())
+	}
 
 	return id, nil
 }
 
-func (t *Trade) LiquidateTradeDeal(ctx context.Context, orderId uuid.UUID, closePrice decimal.Decimal) (uuid.UUID, error) {
-	const op = "trade.LiquidateTradeDeal"
-	orderId, err := t.orderService.LiquidateOrder(ctx, orderId, closePrice)
+// UpdateOrderStops sets new stop-loss and take-profit of user's open or pending order
+func (t *Trade) UpdateOrderStops(ctx context.Context, userId int64, orderId uuid.UUID, stops models.Stops) (uuid.UUID, error) {
+	const op = "Trade.UpdateOrderStops"
+
+	order, err := t.orderService.GetUserOrder(ctx, userId, orderId)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ticker := strings.TrimSpace(order.Ticker)
+
+	// open position is checked against mark price triggering stops, so new levels don't fire immediately
+	refPrice := order.EntryPrice
+	if order.Status == models.Open {
+		refPrice, err = t.markPrice(ctx, ticker)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err := validateStops(order.Type, refPrice, stops); err != nil {
+		return uuid.Nil, err
+	}
+
+	id, err := t.orderService.UpdateOrderStops(ctx, userId, orderId, stops)
+	if err != nil {
+		t.log.Error("Error updating order stops", "error", err, "orderId", orderId)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if order.Status == models.Open {
+		err = t.redis.SaveOrderStops(ctx, models.Order{Ticker: ticker, Type: order.Type, Id: id,
+			StopLoss: stops.StopLoss, TakeProfit: stops.TakeProfit})
+		if err != nil {
+			t.log.Error("Error saving order stops to redis", "error", err, "orderId", id)
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return id, nil
+}
+
+// CloseTriggeredDeal closes position through CloseTradeDeal when its stop-loss,
+// take-profit or trailing stop is crossed by price
+func (t *Trade) CloseTriggeredDeal(ctx context.Context, orderId uuid.UUID, price decimal.Decimal) (uuid.UUID, error) {
+	const op = "Trade.CloseTriggeredDeal"
+
+	order, err := t.orderService.GetOrder(ctx, orderId)
 	if err != nil {
 		t.log.Error("Error getting order", "error", err, "orderId", orderId)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
+
+	if order.Status != models.Open {
+		// index is stale: order was already closed or liquidated
+		t.removeOrderIndexes(ctx, orderId, strings.TrimSpace(order.Ticker), order.Type)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, postgres.ErrOrderNotOpen)
+	}
+	if !stopTriggered(order, price) {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrInvalidStops)
+	}
+
+	id, err := t.CloseTradeDeal(ctx, order.UserId, orderId)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// SetTrailingStop attaches trailing stop to user's open position, nil removes it.
+// Trailing starts from current mark price
+func (t *Trade) SetTrailingStop(ctx context.Context, userId int64, orderId uuid.UUID, ts *models.TrailingStop) (uuid.UUID, error) {
+	const op = "Trade.SetTrailingStop"
+
+	order, err := t.orderService.GetUserOrder(ctx, userId, orderId)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ticker := strings.TrimSpace(order.Ticker)
+
+	if ts != nil {
+		if !ts.Value.IsPositive() || ts.Type == models.TrailingPercent && ts.Value.GreaterThanOrEqual(decimal.NewFromInt(100)) ||
+			ts.Type != models.TrailingPercent && ts.Type != models.TrailingAbsolute {
+			return uuid.Nil, ErrInvalidTrailing
+		}
+
+		ts.Extreme, err = t.markPrice(ctx, ticker)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if !ts.Level(order.Type).IsPositive() {
+			return uuid.Nil, ErrInvalidTrailing
+		}
+	}
+
+	id, err := t.orderService.SetTrailingStop(ctx, userId, orderId, ts)
+	if err != nil {
+		t.log.Error("Error setting trailing stop", "error", err, "orderId", orderId)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = t.redis.SaveTrailingStop(ctx, models.Order{Id: id, Ticker: ticker, Type: order.Type, TrailingStop: ts})
+	if err != nil {
+		t.log.Error("Error saving trailing stop to redis", "error", err, "orderId", id)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// MoveTrailingStop pulls trailing stop after price when price improves its extreme
+func (t *Trade) MoveTrailingStop(ctx context.Context, orderId uuid.UUID, price decimal.Decimal) error {
+	const op = "Trade.MoveTrailingStop"
+
+	order, err := t.orderService.GetOrder(ctx, orderId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	ticker := strings.TrimSpace(order.Ticker)
+
+	if order.Status != models.Open || order.TrailingStop == nil {
+		t.redis.RemoveTrailingStop(ctx, orderId.String(), ticker, order.Type)
+		return nil
+	}
+	if !order.TrailingStop.Improves(order.Type, price) {
+		return nil
+	}
+
+	if err := t.orderService.Manager.UpdateTrailingExtreme(ctx, orderId, price); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	order.TrailingStop.Extreme = price
+	order.Ticker = ticker
+	if err := t.redis.SaveTrailingStop(ctx, order); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.log.Debug("trailing stop moved", "orderId", orderId, "extreme", price, "level", order.TrailingStop.Level(order.Type))
+	return nil
+}
+
+// RestoreTrailingStops rebuilds redis index of trailing stops from postgres
+func (t *Trade) RestoreTrailingStops(ctx context.Context) (int, error) {
+	const op = "Trade.RestoreTrailingStops"
+
+	orders, err := t.orderService.Manager.GetOpenTrailingOrders(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, order := range orders {
+		order.Ticker = strings.TrimSpace(order.Ticker)
+		if err := t.redis.SaveTrailingStop(ctx, order); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return len(orders), nil
+}
+
+// PartialCloseTradeDeal closes fraction (0..1) or fixed notional of user's position
+// at current price. Remaining position keeps its entry price, its liquidation price
+// is recomputed as smaller position may fall into other maintenance margin bracket
+func (t *Trade) PartialCloseTradeDeal(ctx context.Context,
+	userId int64,
+	orderId uuid.UUID,
+	fraction decimal.Decimal,
+	notional decimal.Decimal) (models.OrderClose, decimal.Decimal, error) {
+	const op = "Trade.PartialCloseTradeDeal"
+
+	if fraction.IsPositive() == notional.IsPositive() {
+		return models.OrderClose{}, decimal.Zero, ErrInvalidCloseAmount
+	}
+
+	order, err := t.orderService.GetUserOrder(ctx, userId, orderId)
+	if err != nil {
+		return models.OrderClose{}, decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+	ticker := strings.TrimSpace(order.Ticker)
+
+	if notional.IsPositive() {
+		positionNotional := order.Margin.Mul(decimal.NewFromInt(int64(order.Leverage)))
+		fraction = notional.Div(positionNotional)
+	}
+	closedMargin := order.Margin.Mul(fraction).RoundDown(2)
+	if !closedMargin.IsPositive() || closedMargin.GreaterThanOrEqual(order.Margin) {
+		return models.OrderClose{}, decimal.Zero, ErrInvalidCloseAmount
+	}
+	// extra margin is released in the same proportion
+	closedExtraMargin := order.ExtraMargin.Mul(fraction).RoundDown(2)
+
+	rest := order
+	rest.Margin = order.Margin.Sub(closedMargin)
+	rest.ExtraMargin = order.ExtraMargin.Sub(closedExtraMargin)
+	liqPrice, err := t.liquidationPrice(ticker, rest)
+	if err != nil {
+		return models.OrderClose{}, decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	closePriceDec, err := t.lastPrice(ctx, ticker)
+	if err != nil {
+		return models.OrderClose{}, decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	closedPart := order
+	closedPart.Margin = closedMargin
+	pnl := calculateOrderProfit(closedPart, closePriceDec).Round(2)
+
+	closeFee, err := t.tradeFee(ctx, userId, ticker, models.CloseFee, models.Taker, closeNotional(closedPart, closePriceDec))
+	if err != nil {
+		return models.OrderClose{}, decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+	released := closedMargin.Add(closedExtraMargin)
+	credit, fundEntries := settleClose(released.Add(pnl), &closeFee)
+	// user realizes loss up to released margin, insurance fund covers the rest
+	pnl = credit.Add(closeFee.Amount).Sub(released)
+
+	remaining, err := t.orderService.PartialCloseOrder(ctx, userId, order, closedMargin, closedExtraMargin,
+		closePriceDec, pnl, closeFee, fundEntries, liqPrice)
+	if err != nil {
+		t.log.Error("Error partially closing order", "error", err, "orderId", orderId)
+		return models.OrderClose{}, decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// re-score order in orders:long:/orders:short: sorted set
+	err = t.redis.SaveOrder(ctx, models.Order{Ticker: ticker, Type: order.Type, Id: orderId, LiquidationPrice: liqPrice})
+	if err != nil {
+		t.log.Error("Error saving order to redis", "error", err, "orderId", orderId)
+		return models.OrderClose{}, decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(fundEntries) > 0 {
+		t.log.Warn("position partially closed past bankruptcy price", "orderId", orderId, "loss", credit.Neg())
+	}
+
+	return models.OrderClose{
+		OrderId:     orderId,
+		Margin:      released,
+		ClosePrice:  closePriceDec,
+		RealizedPnl: pnl,
+		Fee:         closeFee.Amount,
+	}, remaining, nil
+}
+
+// AddMargin moves amount from user balance to isolated margin of open position
+func (t *Trade) AddMargin(ctx context.Context, userId int64, orderId uuid.UUID, amount decimal.Decimal) (models.Order, error) {
+	const op = "Trade.AddMargin"
+
+	if !amount.IsPositive() {
+		return models.Order{}, ErrInvalidMargin
+	}
+
+	order, err := t.adjustMargin(ctx, userId, orderId, amount)
+	if err != nil {
+		return models.Order{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return order, nil
+}
+
+// WithdrawMargin returns previously added isolated margin to user balance.
+// Withdrawal is refused if new liquidation price is already crossed by current price
+func (t *Trade) WithdrawMargin(ctx context.Context, userId int64, orderId uuid.UUID, amount decimal.Decimal) (models.Order, error) {
+	const op = "Trade.WithdrawMargin"
+
+	if !amount.IsPositive() {
+		return models.Order{}, ErrInvalidMargin
+	}
+
+	order, err := t.adjustMargin(ctx, userId, orderId, amount.Neg())
+	if err != nil {
+		return models.Order{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return order, nil
+}
+
+func (t *Trade) adjustMargin(ctx context.Context, userId int64, orderId uuid.UUID, delta decimal.Decimal) (models.Order, error) {
+	order, err := t.orderService.GetUserOrder(ctx, userId, orderId)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if order.Status != models.Open {
+		return models.Order{}, postgres.ErrOrderNotOpen
+	}
+	ticker := strings.TrimSpace(order.Ticker)
+
+	newExtraMargin := order.ExtraMargin.Add(delta)
+	if newExtraMargin.IsNegative() {
+		return models.Order{}, ErrInvalidMargin
+	}
+	position := order
+	position.ExtraMargin = newExtraMargin
+	liqPrice, err := t.liquidationPrice(ticker, position)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	if delta.IsNegative() {
+		// position is liquidated by mark price
+		priceDec, err := t.markPrice(ctx, ticker)
+		if err != nil {
+			return models.Order{}, err
+		}
+		if order.Type == models.Long && liqPrice.GreaterThanOrEqual(priceDec) ||
+			order.Type == models.Short && liqPrice.LessThanOrEqual(priceDec) {
+			return models.Order{}, ErrWithdrawLiquidates
+		}
+	}
+
+	order.ExtraMargin, err = t.orderService.AdjustMargin(ctx, userId, order, delta, liqPrice)
+	if err != nil {
+		t.log.Error("Error adjusting margin", "error", err, "orderId", orderId)
+		return models.Order{}, err
+	}
+	order.LiquidationPrice = liqPrice
+
+	// re-score order in orders:long:/orders:short: sorted set
+	err = t.redis.SaveOrder(ctx, models.Order{Ticker: ticker, Type: order.Type, Id: order.Id, LiquidationPrice: liqPrice})
+	if err != nil {
+		t.log.Error("Error saving order to redis", "error", err, "orderId", order.Id)
+		return models.Order{}, err
+	}
+
+	return order, nil
+}
+
+// GetOrderCloses returns partial closes of user's order
+func (t *Trade) GetOrderCloses(ctx context.Context, userId int64, orderId uuid.UUID) ([]models.OrderClose, error) {
+	const op = "Trade.GetOrderCloses"
+
+	if _, err := t.orderService.GetUserOrder(ctx, userId, orderId); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	closes, err := t.orderService.Manager.GetOrderCloses(ctx, orderId)
+	if err != nil {
+		t.log.Error("Error getting order closes", "error", err, "orderId", orderId)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return closes, nil
+}
+
+// GetOrderFees returns trading fees paid for user's order
+func (t *Trade) GetOrderFees(ctx context.Context, userId int64, orderId uuid.UUID) ([]models.OrderFee, error) {
+	const op = "Trade.GetOrderFees"
+
+	if _, err := t.orderService.GetUserOrder(ctx, userId, orderId); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orderFees, err := t.orderService.Manager.GetOrderFees(ctx, orderId)
+	if err != nil {
+		t.log.Error("Error getting order fees", "error", err, "orderId", orderId)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orderFees, nil
+}
+
+// ExportUserOrders streams user's orders matching filter to fn, oldest first
+func (t *Trade) ExportUserOrders(ctx context.Context, userId int64, filter models.OrderExportFilter, fn func(models.OrderExport) error) error {
+	const op = "Trade.ExportUserOrders"
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return ErrInvalidPeriod
+	}
+	filter.Ticker = strings.TrimSpace(filter.Ticker)
+
+	if err := t.orderService.Manager.StreamUserOrders(ctx, userId, filter, fn); err != nil {
+		t.log.Error("Error exporting user orders", "error", err, "userId", userId)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// tradeFee prices trade by fee tier of user's 30-day volume
+func (t *Trade) tradeFee(ctx context.Context,
+	userId int64,
+	ticker string,
+	kind models.FeeKind,
+	liquidity models.Liquidity,
+	notional decimal.Decimal) (models.OrderFee, error) {
+	volume, err := t.orderService.Manager.GetUserVolume(ctx, userId, time.Now().Add(-fees.VolumeWindow))
+	if err != nil {
+		t.log.Error("Error getting user volume", "error", err, "userId", userId)
+		return models.OrderFee{}, err
+	}
+
+	return t.fees.Fee(ticker, volume, kind, liquidity, notional), nil
+}
+
+// LiquidateTradeDeal closes position triggered by triggerPrice at current mark price, if that
+// is still past liquidation price. Equity left above bankruptcy price goes to insurance fund,
+// loss past bankruptcy price is covered by it
+func (t *Trade) LiquidateTradeDeal(ctx context.Context, orderId uuid.UUID, triggerPrice decimal.Decimal) (uuid.UUID, error) {
+	const op = "trade.LiquidateTradeDeal"
+
 	curOrder, err := t.orderService.GetOrder(ctx, orderId)
 	if err != nil {
 		t.log.Error("Error getting order", "error", err, "orderId", orderId)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ticker := strings.TrimSpace(curOrder.Ticker)
+	closePrice, err := t.freshMarkPrice(ctx, ticker)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !priceReached(curOrder.Type, curOrder.LiquidationPrice, closePrice) {
+		t.log.Info("Liquidation price is not reached at current mark price", "orderId", orderId,
+			"triggerPrice", triggerPrice, "markPrice", closePrice)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrNotLiquidatable)
+	}
+
+	settlement := t.riskModel.Settle(ticker, risk.PositionOf(curOrder), closePrice)
+	fundEntries := []models.InsuranceFundEntry{
+		{Reason: models.InsuranceLiquidationFee, Amount: settlement.Fee},
+		{Reason: models.InsuranceSurplus, Amount: settlement.Surplus},
+		{Reason: models.InsuranceBankruptcyLoss, Amount: settlement.Loss.Neg()},
+	}
+
+	orderId, err = t.orderService.LiquidateOrder(ctx, orderId, closePrice, settlement.Fee, fundEntries)
+	if err != nil {
+		if errors.Is(err, postgres.ErrOrderNotOpen) {
+			t.removeOrderIndexes(ctx, curOrder.Id, ticker, curOrder.Type)
+		}
+		t.log.Error("Error liquidating order", "error", err, "orderId", curOrder.Id)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.removeOrderIndexes(ctx, orderId, ticker, curOrder.Type)
+	if settlement.Loss.IsPositive() {
+		t.log.Warn("position liquidated past bankruptcy price", "orderId", orderId, "loss", settlement.Loss)
 	}
-	t.redis.RemoveOrder(ctx, orderId.String(), strings.TrimSpace(curOrder.Ticker), curOrder.Type)
 	return orderId, nil
 }
 
+// validateStops checks that stop-loss is below and take-profit is above price for long, and vice versa for short
+func validateStops(orderType models.OrderType, price decimal.Decimal, stops models.Stops) error {
+	if stops.StopLoss != nil {
+		if !stops.StopLoss.IsPositive() {
+			return ErrInvalidStops
+		}
+		if orderType == models.Long && stops.StopLoss.GreaterThanOrEqual(price) ||
+			orderType == models.Short && stops.StopLoss.LessThanOrEqual(price) {
+			return ErrInvalidStops
+		}
+	}
+
+	if stops.TakeProfit != nil {
+		if !stops.TakeProfit.IsPositive() {
+			return ErrInvalidStops
+		}
+		if orderType == models.Long && stops.TakeProfit.LessThanOrEqual(price) ||
+			orderType == models.Short && stops.TakeProfit.GreaterThanOrEqual(price) {
+			return ErrInvalidStops
+		}
+	}
+
+	return nil
+}
+
+// stopTriggered reports whether price crossed stop-loss, take-profit or trailing stop of order
+func stopTriggered(order models.Order, price decimal.Decimal) bool {
+	if order.TrailingStop != nil {
+		level := order.TrailingStop.Level(order.Type)
+		if order.Type == models.Long && price.LessThanOrEqual(level) ||
+			order.Type == models.Short && price.GreaterThanOrEqual(level) {
+			return true
+		}
+	}
+
+	if order.Type == models.Long {
+		return order.StopLoss != nil && price.LessThanOrEqual(*order.StopLoss) ||
+			order.TakeProfit != nil && price.GreaterThanOrEqual(*order.TakeProfit)
+	}
+
+	return order.StopLoss != nil && price.GreaterThanOrEqual(*order.StopLoss) ||
+		order.TakeProfit != nil && price.LessThanOrEqual(*order.TakeProfit)
+}
+
+// priceReached reports whether price reached level from position's losing side:
+// long is triggered at or below level, short at or above it
+func priceReached(orderType models.OrderType, level, price decimal.Decimal) bool {
+	if orderType == models.Long {
+		return price.LessThanOrEqual(level)
+	}
+	return price.GreaterThanOrEqual(level)
+}
+
+// removeOrderIndexes drops order from liquidation, stop and trailing sorted sets
+func (t *Trade) removeOrderIndexes(ctx context.Context, id uuid.UUID, ticker string, orderType models.OrderType) {
+	t.redis.RemoveOrder(ctx, id.String(), ticker, orderType)
+	t.redis.RemoveOrderStops(ctx, id.String(), ticker, orderType)
+	t.redis.RemoveTrailingStop(ctx, id.String(), ticker, orderType)
+}
+
+// liquidationPrice prices position of order (with its extra margin) in risk model
+func (t *Trade) liquidationPrice(ticker string, order models.Order) (decimal.Decimal, error) {
+	return t.riskModel.LiquidationPrice(ticker, risk.PositionOf(order))
+}
+
+func positionNotional(margin decimal.Decimal, leverage uint8) decimal.Decimal {
+	return margin.Mul(decimal.NewFromInt(int64(leverage)))
+}
+
+// settleClose caps close fee at credit closed part of position returns to wallet and floors
+// credit at zero: loss past released margin is not taken from wallet, insurance fund covers it
+func settleClose(credit decimal.Decimal, closeFee *models.OrderFee) (decimal.Decimal, []models.InsuranceFundEntry) {
+	credit = credit.Round(2)
+	closeFee.Amount = decimal.Min(closeFee.Amount, decimal.Max(credit, decimal.Zero))
+	credit = credit.Sub(closeFee.Amount)
+	if !credit.IsNegative() {
+		return credit, nil
+	}
+	return decimal.Zero, []models.InsuranceFundEntry{{Reason: models.InsuranceBankruptcyLoss, Amount: credit}}
+}
+
+// closeNotional is position notional at close price
+func closeNotional(order models.Order, closePrice decimal.Decimal) decimal.Decimal {
+	return positionNotional(order.Margin, order.Leverage).Mul(closePrice).Div(order.EntryPrice)
+}
+
 func calculateOrderProfit(order models.Order, closePriceDec decimal.Decimal) decimal.Decimal {
 	priceDiff := closePriceDec.Sub(order.EntryPrice)
 	priceChange := priceDiff.Div(order.EntryPrice)
@@ -0,0 +1,47 @@
+package trade
+
+import (
+	"Exchange/internal/domain/models"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestSettleClose(t *testing.T) {
+	tests := []struct {
+		name       string
+		credit     string
+		fee        string
+		wantCredit string
+		wantFee    string
+		wantLoss   string
+	}{
+		{name: "profit", credit: "150", fee: "2", wantCredit: "148", wantFee: "2"},
+		{name: "fee capped at credit", credit: "1.5", fee: "2", wantCredit: "0", wantFee: "1.5"},
+		{name: "credit rounded to cents", credit: "10.004", fee: "0.5", wantCredit: "9.5", wantFee: "0.5"},
+		{name: "loss past margin", credit: "-30.25", fee: "2", wantCredit: "0", wantFee: "0", wantLoss: "-30.25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee := models.OrderFee{Amount: decimal.RequireFromString(tt.fee)}
+			credit, entries := settleClose(decimal.RequireFromString(tt.credit), &fee)
+
+			if !credit.Equal(decimal.RequireFromString(tt.wantCredit)) {
+				t.Errorf("got credit %s, want %s", credit, tt.wantCredit)
+			}
+			if !fee.Amount.Equal(decimal.RequireFromString(tt.wantFee)) {
+				t.Errorf("got fee %s, want %s", fee.Amount, tt.wantFee)
+			}
+			if tt.wantLoss == "" {
+				if len(entries) != 0 {
+					t.Fatalf("got fund entries %v, want none", entries)
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].Reason != models.InsuranceBankruptcyLoss ||
+				!entries[0].Amount.Equal(decimal.RequireFromString(tt.wantLoss)) {
+				t.Fatalf("got fund entries %v, want bankruptcy loss %s", entries, tt.wantLoss)
+			}
+		})
+	}
+}
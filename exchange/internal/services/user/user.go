@@ -1,7 +1,9 @@
 package user
 
 import (
+	"Exchange/internal/config"
 	"Exchange/internal/domain/models"
+	"Exchange/internal/lib/jwt"
 	"Exchange/internal/storage/postgres"
 	"context"
 	"errors"
@@ -17,17 +19,33 @@ var (
 	ErrInsufficientFunds  = errors.New("Insufficient funds")
 	ErrInvalidAmount      = errors.New("Invalid amount")
 	ErrInvalidCredentials = errors.New("Invalid credentials")
+	ErrInvalidToken       = errors.New("Invalid token")
+	ErrInvalidPeriod      = errors.New("Invalid period")
+)
+
+const (
+	DefaultTransactionsLimit = 100
+	MaxTransactionsLimit     = 1000
 )
 
 type UserService struct {
 	log            slog.Logger
 	manager        Manager
 	balanceManager BalanceManager
+	sessions       SessionManager
+	authCfg        config.AuthConfig
 }
 
 func (us *UserService) GetUserOrders(ctx context.Context, id int64) ([]models.Order, error) {
-	//TODO implement me
-	panic("implement me")
+	const op = "user.GetUserOrders"
+
+	orders, err := us.manager.GetUserOrders(ctx, id)
+	if err != nil {
+		us.log.Error("Failed to get user orders", "id", id, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orders, nil
 }
 
 type Manager interface {
@@ -38,19 +56,36 @@ type Manager interface {
 		createdAt time.Time) (int64, error)
 	GetUserByEmail(ctx context.Context, email string) (models.User, error)
 	GetUserById(ctx context.Context, id int64) (models.User, error)
+	GetUserOrders(ctx context.Context, userId int64) ([]models.Order, error)
 }
 
 type BalanceManager interface {
 	GetBalance(ctx context.Context, id int64) (decimal.Decimal, error)
 	IncreaseBalance(ctx context.Context, id int64, increaseAmount decimal.Decimal) (decimal.Decimal, error)
 	DecreaseBalance(ctx context.Context, id int64, decreaseAmount decimal.Decimal) (decimal.Decimal, error)
+	GetUserTransactions(ctx context.Context, userId int64, filter models.TransactionFilter, limit, offset int) ([]models.Transaction, error)
+	GetUserStatement(ctx context.Context, userId int64, from, to time.Time) (models.Statement, error)
+}
+
+type SessionManager interface {
+	SaveRefreshSession(ctx context.Context, userId int64, jti string, ttl time.Duration) error
+	ConsumeRefreshSession(ctx context.Context, userId int64, jti string) (bool, error)
+	RevokeToken(ctx context.Context, jti string, ttl time.Duration) error
+	RevokeUserSessions(ctx context.Context, userId int64, ttl time.Duration) error
+	IsTokenRevoked(ctx context.Context, userId int64, jti string, issuedAt time.Time) (bool, error)
 }
 
-func New(log slog.Logger, manager Manager, balanceManager BalanceManager) *UserService {
+func New(log slog.Logger,
+	manager Manager,
+	balanceManager BalanceManager,
+	sessions SessionManager,
+	authCfg config.AuthConfig) *UserService {
 	return &UserService{
 		log:            log,
 		manager:        manager,
 		balanceManager: balanceManager,
+		sessions:       sessions,
+		authCfg:        authCfg,
 	}
 }
 
@@ -76,66 +111,144 @@ func (us *UserService) RegisterNewUser(ctx context.Context, email string, passwo
 	return id, nil
 }
 
-func (us *UserService) Login(ctx context.Context, email, password string) (int64, string, error) {
+func (us *UserService) Login(ctx context.Context, email, password string) (models.User, models.TokenPair, error) {
 	const op = "user.Login"
 
 	user, err := us.manager.GetUserByEmail(ctx, email)
 	if err != nil {
-		slog.Error("Failed to get user by email", "email", email, "err", err, "op", op)
-		return 0, "", fmt.Errorf("%s: %w", op, err)
+		us.log.Error("Failed to get user by email", "email", email, "err", err, "op", op)
+		return models.User{}, models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PassHash), []byte(password)); err != nil {
-		slog.Error("invalid credentials", slog.String("error", err.Error()))
+		us.log.Error("invalid credentials", slog.String("error", err.Error()))
 
-		return 0, "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		return models.User{}, models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
-	return user.Id, user.Email, nil
+	tokens, err := us.issueTokens(ctx, user.Id, user.Email)
+	if err != nil {
+		us.log.Error("failed to issue tokens", "id", user.Id, "err", err)
+		return models.User{}, models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, tokens, nil
 }
 
-/*
-func (a *Auth) Login(
-	ctx context.Context,
-	email, password string,
-	appID int) (string, error) {
-	const op = "auth.Login"
+// Refresh exchanges refresh token for a new token pair. Refresh token is single-use
+func (us *UserService) Refresh(ctx context.Context, refreshToken string) (models.TokenPair, error) {
+	const op = "user.Refresh"
 
-	log := a.log.With(slog.String("op", op),
-		slog.String("email", email))
+	claims, err := jwt.ParseToken(refreshToken, jwt.RefreshToken, us.authCfg.Secret)
+	if err != nil {
+		us.log.Info("invalid refresh token", "err", err)
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
 
-	user, err := a.userProvider.User(ctx, email)
+	existed, err := us.sessions.ConsumeRefreshSession(ctx, claims.UserID, claims.ID)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Warn("user not found", slog.String("error", err.Error()))
+		us.log.Error("failed to consume refresh session", "id", claims.UserID, "err", err)
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !existed {
+		us.log.Info("refresh token reused or revoked", "id", claims.UserID)
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
 
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
+	tokens, err := us.issueTokens(ctx, claims.UserID, claims.Email)
+	if err != nil {
+		us.log.Error("failed to issue tokens", "id", claims.UserID, "err", err)
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes access token of current session and its refresh token if passed
+func (us *UserService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	const op = "user.Logout"
+
+	claims, err := jwt.ParseToken(accessToken, jwt.AccessToken, us.authCfg.Secret)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := us.sessions.RevokeToken(ctx, claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		us.log.Error("failed to revoke access token", "id", claims.UserID, "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	refreshClaims, err := jwt.ParseToken(refreshToken, jwt.RefreshToken, us.authCfg.Secret)
+	if err != nil || refreshClaims.UserID != claims.UserID {
+		return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	if _, err := us.sessions.ConsumeRefreshSession(ctx, claims.UserID, refreshClaims.ID); err != nil {
+		us.log.Error("failed to revoke refresh session", "id", claims.UserID, "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeSessions logs user out on every device
+func (us *UserService) RevokeSessions(ctx context.Context, userId int64) error {
+	const op = "user.RevokeSessions"
 
-		log.Error("failed to get user", slog.String("error", err.Error()))
+	if err := us.sessions.RevokeUserSessions(ctx, userId, us.authCfg.RefreshTTL); err != nil {
+		us.log.Error("failed to revoke sessions", "id", userId, "err", err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		log.Error("invalid credentials", slog.String("error", err.Error()))
+	return nil
+}
+
+// Authenticate validates access token and returns its owner id
+func (us *UserService) Authenticate(ctx context.Context, accessToken string) (int64, error) {
+	const op = "user.Authenticate"
 
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	claims, err := jwt.ParseToken(accessToken, jwt.AccessToken, us.authCfg.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	revoked, err := us.sessions.IsTokenRevoked(ctx, claims.UserID, claims.ID, claims.IssuedAtTime())
+	if err != nil {
+		us.log.Error("failed to check token revocation", "id", claims.UserID, "err", err)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidToken)
 	}
 
-	app, err := a.appProvider.App(ctx, int64(appID))
+	return claims.UserID, nil
+}
+
+func (us *UserService) issueTokens(ctx context.Context, userId int64, email string) (models.TokenPair, error) {
+	access, accessClaims, err := jwt.NewToken(userId, email, jwt.AccessToken, us.authCfg.AccessTTL, us.authCfg.Secret)
 	if err != nil {
-		log.Error("failed to get app", slog.String("error", err.Error()))
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.TokenPair{}, err
 	}
 
-	token, err := jwt.NewToken(user, app, a.tokenTTL)
+	refresh, refreshClaims, err := jwt.NewToken(userId, email, jwt.RefreshToken, us.authCfg.RefreshTTL, us.authCfg.Secret)
 	if err != nil {
-		log.Error("failed to create token", slog.String("error", err.Error()))
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.TokenPair{}, err
 	}
 
-	return token, nil
+	if err := us.sessions.SaveRefreshSession(ctx, userId, refreshClaims.ID, us.authCfg.RefreshTTL); err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return models.TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		AccessExpiresAt:  accessClaims.ExpiresAt.Time,
+		RefreshExpiresAt: refreshClaims.ExpiresAt.Time,
+	}, nil
 }
-*/
 
 func (us *UserService) GetBalance(ctx context.Context, id int64) (decimal.Decimal, error) {
 	const op = "user.GetBalance"
@@ -192,3 +305,45 @@ func (us *UserService) DecreaseBalance(ctx context.Context, id int64, decreaseAm
 
 	return updatedBalance, nil
 }
+
+// GetTransactions returns page of user's wallet movements with running balance, newest first
+func (us *UserService) GetTransactions(ctx context.Context, id int64, filter models.TransactionFilter, limit, offset int) ([]models.Transaction, error) {
+	const op = "user.GetTransactions"
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, ErrInvalidPeriod
+	}
+	if limit <= 0 {
+		limit = DefaultTransactionsLimit
+	}
+	limit = min(limit, MaxTransactionsLimit)
+	offset = max(offset, 0)
+
+	transactions, err := us.balanceManager.GetUserTransactions(ctx, id, filter, limit, offset)
+	if err != nil {
+		us.log.Error("Failed to get transactions", "id", id, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return transactions, nil
+}
+
+// GetStatement summarizes user's wallet over [from, to), zero to means now
+func (us *UserService) GetStatement(ctx context.Context, id int64, from, to time.Time) (models.Statement, error) {
+	const op = "user.GetStatement"
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if !from.Before(to) {
+		return models.Statement{}, ErrInvalidPeriod
+	}
+
+	statement, err := us.balanceManager.GetUserStatement(ctx, id, from, to)
+	if err != nil {
+		us.log.Error("Failed to get statement", "id", id, "err", err)
+		return models.Statement{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return statement, nil
+}
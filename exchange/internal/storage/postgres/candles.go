@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SaveCandles merges bars built from price ticks into stored ones: open of stored bar
+// is kept, high and low are widened, close is replaced. Volume is not known from ticks
+func (s *Storage) SaveCandles(ctx context.Context, candles []models.Candle) error {
+	const op = "postgresql.SaveCandles"
+
+	const querySave = `
+        INSERT INTO candles (ticker, candle_interval, open_time, open, high, low, close)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (ticker, candle_interval, open_time) DO UPDATE SET
+            high  = GREATEST(candles.high, EXCLUDED.high),
+            low   = LEAST(candles.low, EXCLUDED.low),
+            close = EXCLUDED.close`
+	return s.writeCandles(ctx, op, querySave, candles, false)
+}
+
+// ReplaceCandles stores bars loaded from exchange as they are, including volume
+func (s *Storage) ReplaceCandles(ctx context.Context, candles []models.Candle) error {
+	const op = "postgresql.ReplaceCandles"
+
+	const queryReplace = `
+        INSERT INTO candles (ticker, candle_interval, open_time, open, high, low, close, volume)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (ticker, candle_interval, open_time) DO UPDATE SET
+            open   = EXCLUDED.open,
+            high   = EXCLUDED.high,
+            low    = EXCLUDED.low,
+            close  = EXCLUDED.close,
+            volume = EXCLUDED.volume`
+	return s.writeCandles(ctx, op, queryReplace, candles, true)
+}
+
+func (s *Storage) writeCandles(ctx context.Context, op, query string, candles []models.Candle, withVolume bool) error {
+	log := slog.With("op", op)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		log.Error("Failed to begin transaction", "err", err)
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, c := range candles {
+		args := []any{c.Ticker, c.Interval, c.OpenTime, c.Open, c.High, c.Low, c.Close}
+		if withVolume {
+			args = append(args, c.Volume)
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			log.Error("Failed to save candle", "ticker", c.Ticker, "interval", c.Interval, "open_time", c.OpenTime, "err", err)
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error("Failed to commit transaction", "err", err)
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetLastCandleTime returns open time of latest stored bar, zero time if there are none
+func (s *Storage) GetLastCandleTime(ctx context.Context, ticker, interval string) (time.Time, error) {
+	const op = "postgresql.GetLastCandleTime"
+
+	var last *time.Time
+	err := s.db.QueryRow(ctx, `SELECT MAX(open_time) FROM candles WHERE ticker = $1 AND candle_interval = $2`,
+		ticker, interval,
+	).Scan(&last)
+	if err != nil {
+		slog.Error("Failed to get last candle time", "op", op, "ticker", ticker, "interval", interval, "err", err)
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if last == nil {
+		return time.Time{}, nil
+	}
+
+	return *last, nil
+}
+
+// GetCandles returns up to limit bars opened in [from, to), oldest first
+func (s *Storage) GetCandles(ctx context.Context, ticker, interval string, from, to time.Time, limit int) ([]models.Candle, error) {
+	const op = "postgresql.GetCandles"
+	log := slog.With("op", op)
+
+	const queryGetCandles = `
+        SELECT ticker, candle_interval, open_time, open, high, low, close, volume
+        FROM candles WHERE ticker = $1 AND candle_interval = $2 AND open_time >= $3 AND open_time < $4
+        ORDER BY open_time LIMIT $5`
+	rows, err := s.db.Query(ctx, queryGetCandles, ticker, interval, from, to, limit)
+	if err != nil {
+		log.Error("Failed to get candles", "ticker", ticker, "interval", interval, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	candles := []models.Candle{}
+	for rows.Next() {
+		var c models.Candle
+		err := rows.Scan(&c.Ticker, &c.Interval, &c.OpenTime, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume)
+		if err != nil {
+			log.Error("Failed to scan candle", "ticker", ticker, "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		candles = append(candles, c)
+	}
+
+	return candles, rows.Err()
+}
+
+// GetCandlesBefore returns up to limit latest bars opened before to, oldest first
+func (s *Storage) GetCandlesBefore(ctx context.Context, ticker, interval string, to time.Time, limit int) ([]models.Candle, error) {
+	const op = "postgresql.GetCandlesBefore"
+	log := slog.With("op", op)
+
+	const queryGetCandles = `
+        SELECT ticker, candle_interval, open_time, open, high, low, close, volume
+        FROM (SELECT * FROM candles WHERE ticker = $1 AND candle_interval = $2 AND open_time < $3
+              ORDER BY open_time DESC LIMIT $4) c
+        ORDER BY open_time`
+	rows, err := s.db.Query(ctx, queryGetCandles, ticker, interval, to, limit)
+	if err != nil {
+		log.Error("Failed to get candles", "ticker", ticker, "interval", interval, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	candles := []models.Candle{}
+	for rows.Next() {
+		var c models.Candle
+		err := rows.Scan(&c.Ticker, &c.Interval, &c.OpenTime, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume)
+		if err != nil {
+			log.Error("Failed to scan candle", "ticker", ticker, "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		candles = append(candles, c)
+	}
+
+	return candles, rows.Err()
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// GetTradingPairs returns all trading pairs ordered by base asset
+func (s *Storage) GetTradingPairs(ctx context.Context) ([]models.TradingPair, error) {
+	const op = "postgresql.GetTradingPairs"
+	log := slog.With("op", op)
+
+	rows, err := s.db.Query(ctx, `SELECT id, base_asset, quote_asset FROM trading_pairs ORDER BY base_asset, quote_asset`)
+	if err != nil {
+		log.Error("Failed to get trading pairs", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	pairs := []models.TradingPair{}
+	for rows.Next() {
+		var p models.TradingPair
+		if err := rows.Scan(&p.Id, &p.BaseAsset, &p.QuoteAsset); err != nil {
+			log.Error("Failed to scan trading pair", "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		pairs = append(pairs, p)
+	}
+
+	return pairs, rows.Err()
+}
+
+// GetUserChartMarks returns up to limit latest entries, exits and liquidations of user's
+// orders of ticker made in [from, to), oldest first. Partial closes are exits too
+func (s *Storage) GetUserChartMarks(ctx context.Context, userId int64, ticker string, from, to time.Time, limit int) ([]models.ChartMark, error) {
+	const op = "postgresql.GetUserChartMarks"
+	log := slog.With("op", op)
+
+	const queryGetMarks = `
+        SELECT id, type, kind, price, mark_time
+        FROM (SELECT id, type, $6::text AS kind, entry_price AS price, opened_at AS mark_time
+              FROM orders
+              WHERE user_id = $1 AND ticker = $2 AND opened_at >= $3 AND opened_at < $4
+              UNION ALL
+              SELECT id, type, CASE WHEN status = $8 THEN $9::text ELSE $7::text END, close_price, closed_at
+              FROM orders
+              WHERE user_id = $1 AND ticker = $2 AND closed_at >= $3 AND closed_at < $4
+              UNION ALL
+              SELECT o.id, o.type, $7::text, c.close_price, c.created_at
+              FROM order_closes c
+              JOIN orders o ON o.id = c.order_id
+              WHERE o.user_id = $1 AND o.ticker = $2 AND c.created_at >= $3 AND c.created_at < $4
+              ORDER BY mark_time DESC
+              LIMIT $5) m
+        ORDER BY mark_time`
+	rows, err := s.db.Query(ctx, queryGetMarks, userId, ticker, from, to, limit,
+		models.MarkEntry, models.MarkExit, models.Liquidated, models.MarkLiquidation)
+	if err != nil {
+		log.Error("Failed to get chart marks", "user_id", userId, "ticker", ticker, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	marks := []models.ChartMark{}
+	for rows.Next() {
+		var m models.ChartMark
+		if err := rows.Scan(&m.OrderId, &m.Type, &m.Kind, &m.Price, &m.Time); err != nil {
+			log.Error("Failed to scan chart mark", "user_id", userId, "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		marks = append(marks, m)
+	}
+
+	return marks, rows.Err()
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// StreamUserOrders passes user's orders matching filter to fn one by one, oldest first.
+// Rows are read from connection as fn consumes them, so history is never held in memory.
+// Realized pnl comes from ledger, orders closed before ledger was introduced fall back to recorded closes
+func (s *Storage) StreamUserOrders(ctx context.Context, userId int64, filter models.OrderExportFilter, fn func(models.OrderExport) error) error {
+	const op = "postgresql.StreamUserOrders"
+	log := slog.With("op", op)
+
+	const queryStreamOrders = `
+        SELECT o.id, RTRIM(o.ticker), o.type, o.status, o.leverage, o.margin, o.extra_margin,
+               o.entry_price, o.close_price, COALESCE(l.pnl, c.pnl, 0),
+               o.open_fee + o.close_fee + o.liquidation_fee, o.funding, o.created_at, o.closed_at
+        FROM orders o
+        LEFT JOIN LATERAL (
+            SELECT SUM(amount) AS pnl
+            FROM ledger_entries
+            WHERE order_id = o.id AND user_id = o.user_id
+              AND ((account = $2 AND kind = $3) OR (account = $4 AND kind = $5))
+        ) l ON TRUE
+        LEFT JOIN LATERAL (
+            SELECT SUM(realized_pnl) AS pnl
+            FROM order_closes
+            WHERE order_id = o.id
+        ) c ON TRUE
+        WHERE o.user_id = $1
+          AND ($6 = '' OR o.ticker = $6)
+          AND ($7::timestamptz IS NULL OR o.created_at >= $7)
+          AND ($8::timestamptz IS NULL OR o.created_at < $8)
+        ORDER BY o.created_at, o.id`
+	rows, err := s.db.Query(ctx, queryStreamOrders, userId,
+		models.AccountWallet, models.LedgerRealizedPnl, models.AccountMargin, models.LedgerLiquidation,
+		filter.Ticker, nullTime(filter.From), nullTime(filter.To))
+	if err != nil {
+		log.Error("Failed to stream user orders", "user_id", userId, "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o models.OrderExport
+		err := rows.Scan(&o.Id, &o.Ticker, &o.Type, &o.Status, &o.Leverage, &o.Margin, &o.ExtraMargin,
+			&o.EntryPrice, &o.ClosePrice, &o.RealizedPnl, &o.Fees, &o.Funding, &o.CreatedAt, &o.ClosedAt)
+		if err != nil {
+			log.Error("Failed to scan exported order", "user_id", userId, "err", err)
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := fn(o); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
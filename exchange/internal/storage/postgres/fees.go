@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"time"
+)
+
+// GetUserVolume returns notional traded by user since given time,
+// pending limit orders are not traded yet and are not counted
+func (s *Storage) GetUserVolume(ctx context.Context, userId int64, since time.Time) (decimal.Decimal, error) {
+	const op = "postgresql.GetUserVolume"
+
+	const queryGetVolume = `
+        SELECT COALESCE(SUM(f.notional), 0)
+        FROM order_fees f
+        JOIN orders o ON o.id = f.order_id
+        WHERE f.user_id = $1 AND f.created_at >= $2 AND o.status <> $3`
+	var volume decimal.Decimal
+	err := s.db.QueryRow(ctx, queryGetVolume, userId, since, models.Pending).Scan(&volume)
+	if err != nil {
+		slog.Error("Failed to get user volume", "op", op, "user_id", userId, "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return volume, nil
+}
+
+func (s *Storage) GetOrderFees(ctx context.Context, orderID uuid.UUID) ([]models.OrderFee, error) {
+	const op = "postgresql.GetOrderFees"
+	log := slog.With("op", op)
+
+	const queryGetFees = `
+        SELECT id, order_id, user_id, kind, liquidity, notional, rate, amount, created_at
+        FROM order_fees WHERE order_id = $1 ORDER BY id`
+	rows, err := s.db.Query(ctx, queryGetFees, orderID)
+	if err != nil {
+		log.Error("Failed to get order fees", "order_id", orderID, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	orderFees := []models.OrderFee{}
+	for rows.Next() {
+		var f models.OrderFee
+		err := rows.Scan(&f.Id, &f.OrderId, &f.UserId, &f.Kind, &f.Liquidity, &f.Notional, &f.Rate, &f.Amount, &f.CreatedAt)
+		if err != nil {
+			log.Error("Failed to scan order fee", "order_id", orderID, "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		orderFees = append(orderFees, f)
+	}
+
+	return orderFees, rows.Err()
+}
+
+// insertOrderFee records fee inside tx of operation which charged it
+func insertOrderFee(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, userId int64, fee models.OrderFee, createdAt time.Time) error {
+	_, err := tx.Exec(ctx, `
+        INSERT INTO order_fees (order_id, user_id, kind, liquidity, notional, rate, amount, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		orderID, userId, fee.Kind, fee.Liquidity, fee.Notional, fee.Rate, fee.Amount, createdAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert order fee: %w", err)
+	}
+	return nil
+}
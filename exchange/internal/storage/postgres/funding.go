@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/events"
+	"Exchange/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"slices"
+)
+
+func (s *Storage) GetOpenOrders(ctx context.Context) ([]models.Order, error) {
+	const op = "postgresql.GetOpenOrders"
+	log := slog.With("op", op)
+
+	const queryGetOpen = `SELECT ` + orderColumns + ` FROM orders WHERE status = $1`
+	rows, err := s.db.Query(ctx, queryGetOpen, models.Open)
+	if err != nil {
+		log.Error("Failed to get open orders", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			log.Error("Failed to scan open order", "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// ApplyFundingPayments credits or debits funding of each still open order in one transaction.
+// Debit is taken from user's balance, then from position's extra margin, so neither goes negative.
+// Initial margin backs position size and is not touched: the rest is recorded as payment's shortfall
+// and paid by insurance fund, so receivers are still credited in full. Position whose extra margin
+// was taken is repriced with liquidationPrice and returned. Payment for the same order and
+// funding time is applied once, so settlement can be retried. Returns number of applied payments
+func (s *Storage) ApplyFundingPayments(ctx context.Context, payments []models.FundingPayment,
+	liquidationPrice func(models.Order) (decimal.Decimal, error),
+) (int, []models.Order, error) {
+	const op = "postgresql.ApplyFundingPayments"
+	log := slog.With("op", op)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		log.Error("Failed to begin transaction", "err", err)
+		return 0, nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	applied := 0
+	var (
+		paidUsers []int64
+		repriced  []models.Order
+	)
+	for _, p := range payments {
+		order, err := scanOrder(tx.QueryRow(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1 AND status = $2 FOR UPDATE`,
+			p.OrderId, models.Open,
+		))
+		if errors.Is(err, pgx.ErrNoRows) {
+			// closed after payments were computed
+			continue
+		}
+		if err != nil {
+			log.Error("Failed to get order", "order_id", p.OrderId, "err", err)
+			return 0, nil, fmt.Errorf("%s: get order: %w", op, err)
+		}
+		userID := order.UserId
+
+		walletDelta, fromMargin, shortfall := p.Amount, decimal.Zero, decimal.Zero
+		if p.Amount.IsNegative() {
+			var balance decimal.Decimal
+			err := tx.QueryRow(ctx, `SELECT balance FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&balance)
+			if err != nil {
+				log.Error("Failed to get user balance", "user_id", userID, "err", err)
+				return 0, nil, fmt.Errorf("%s: get balance: %w", op, err)
+			}
+			due := p.Amount.Neg()
+			fromWallet := decimal.Min(due, decimal.Max(balance, decimal.Zero))
+			fromMargin = decimal.Min(due.Sub(fromWallet), order.ExtraMargin)
+			shortfall = due.Sub(fromWallet).Sub(fromMargin)
+			walletDelta = fromWallet.Neg()
+			if shortfall.IsPositive() {
+				log.Warn("Funding payment exceeds balance and extra margin", "order_id", p.OrderId, "user_id", userID,
+					"amount", p.Amount, "shortfall", shortfall)
+			}
+		}
+		amount := walletDelta.Sub(fromMargin)
+
+		tag, err := tx.Exec(ctx, `
+            INSERT INTO funding_payments (order_id, user_id, ticker, rate, mark_price, notional, amount, from_margin, shortfall, funding_time)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+            ON CONFLICT (order_id, funding_time) DO NOTHING`,
+			p.OrderId, userID, p.Ticker, p.Rate, p.MarkPrice, p.Notional, amount, fromMargin, shortfall, p.FundingTime,
+		)
+		if err != nil {
+			log.Error("Failed to record funding payment", "order_id", p.OrderId, "err", err)
+			return 0, nil, fmt.Errorf("%s: record payment: %w", op, err)
+		}
+		if tag.RowsAffected() == 0 {
+			// already settled
+			continue
+		}
+
+		if fromMargin.IsPositive() {
+			order.ExtraMargin = order.ExtraMargin.Sub(fromMargin)
+			order.LiquidationPrice, err = liquidationPrice(order)
+			if err != nil {
+				log.Error("Failed to reprice order", "order_id", p.OrderId, "err", err)
+				return 0, nil, fmt.Errorf("%s: reprice order: %w", op, err)
+			}
+		}
+		_, err = tx.Exec(ctx, `UPDATE orders SET funding = funding + $1, extra_margin = $2, liquidation_price = $3 WHERE id = $4`,
+			amount, order.ExtraMargin, order.LiquidationPrice, p.OrderId,
+		)
+		if err != nil {
+			log.Error("Failed to update order funding", "order_id", p.OrderId, "err", err)
+			return 0, nil, fmt.Errorf("%s: update order: %w", op, err)
+		}
+		_, err = tx.Exec(ctx, `UPDATE users SET balance = balance + $1 WHERE id = $2`, walletDelta, userID)
+		if err != nil {
+			log.Error("Failed to change user balance", "user_id", userID, "err", err)
+			return 0, nil, fmt.Errorf("%s: change balance: %w", op, err)
+		}
+		err = addInsuranceEntries(ctx, tx, p.OrderId, []models.InsuranceFundEntry{
+			{Reason: models.InsuranceFundingShortfall, Amount: shortfall.Neg()},
+		})
+		if err != nil {
+			log.Error("Failed to charge funding shortfall to insurance fund", "order_id", p.OrderId, "err", err)
+			return 0, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		err = postJournal(ctx, tx, userID, &p.OrderId, slices.Concat(
+			transfer(models.LedgerFunding, models.AccountFunding, models.AccountWallet, walletDelta),
+			transfer(models.LedgerFunding, models.AccountMargin, models.AccountFunding, fromMargin),
+			transfer(models.LedgerFunding, models.AccountInsurance, models.AccountFunding, shortfall),
+		)...)
+		if err != nil {
+			log.Error("Failed to journal funding payment", "order_id", p.OrderId, "err", err)
+			return 0, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if fromMargin.IsPositive() {
+			if err := enqueueOrderEvent(ctx, tx, events.OrderChanged, p.OrderId); err != nil {
+				log.Error("Failed to enqueue order event", "order_id", p.OrderId, "err", err)
+				return 0, nil, fmt.Errorf("%s: %w", op, err)
+			}
+			repriced = append(repriced, order)
+		}
+		applied++
+		if !slices.Contains(paidUsers, userID) {
+			paidUsers = append(paidUsers, userID)
+		}
+	}
+
+	for _, userID := range paidUsers {
+		if err := enqueueBalanceEvent(ctx, tx, userID); err != nil {
+			log.Error("Failed to enqueue balance event", "user_id", userID, "err", err)
+			return 0, nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error("Failed to commit transaction", "err", err)
+		return 0, nil, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return applied, repriced, nil
+}
+
+// GetUserFundingPayments returns funding payments of user, newest first
+func (s *Storage) GetUserFundingPayments(ctx context.Context, userId int64, limit, offset int) ([]models.FundingPayment, error) {
+	const op = "postgresql.GetUserFundingPayments"
+	log := slog.With("op", op)
+
+	const queryGetPayments = `
+        SELECT id, order_id, user_id, ticker, rate, mark_price, notional, amount, from_margin, shortfall, funding_time
+        FROM funding_payments WHERE user_id = $1
+        ORDER BY funding_time DESC, id DESC LIMIT $2 OFFSET $3`
+	rows, err := s.db.Query(ctx, queryGetPayments, userId, limit, offset)
+	if err != nil {
+		log.Error("Failed to get funding payments", "user_id", userId, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	payments := []models.FundingPayment{}
+	for rows.Next() {
+		var p models.FundingPayment
+		err := rows.Scan(&p.Id, &p.OrderId, &p.UserId, &p.Ticker, &p.Rate, &p.MarkPrice, &p.Notional, &p.Amount, &p.FromMargin, &p.Shortfall, &p.FundingTime)
+		if err != nil {
+			log.Error("Failed to scan funding payment", "user_id", userId, "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
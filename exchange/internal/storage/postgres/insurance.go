@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"time"
+)
+
+const insuranceFundId = 1
+
+func (s *Storage) GetInsuranceFund(ctx context.Context) (models.InsuranceFund, error) {
+	const op = "postgresql.GetInsuranceFund"
+
+	var fund models.InsuranceFund
+	err := s.db.QueryRow(ctx, `SELECT balance, updated_at FROM insurance_fund WHERE id = $1`, insuranceFundId).
+		Scan(&fund.Balance, &fund.UpdatedAt)
+	if err != nil {
+		slog.Error("Failed to get insurance fund", "op", op, "err", err)
+		return models.InsuranceFund{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fund, nil
+}
+
+// GetInsuranceFundHistory returns fund balance changes, newest first
+func (s *Storage) GetInsuranceFundHistory(ctx context.Context, limit, offset int) ([]models.InsuranceFundEntry, error) {
+	const op = "postgresql.GetInsuranceFundHistory"
+	log := slog.With("op", op)
+
+	const queryGetHistory = `
+        SELECT id, order_id, reason, amount, balance, created_at
+        FROM insurance_fund_history ORDER BY id DESC LIMIT $1 OFFSET $2`
+	rows, err := s.db.Query(ctx, queryGetHistory, limit, offset)
+	if err != nil {
+		log.Error("Failed to get insurance fund history", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	history := []models.InsuranceFundEntry{}
+	for rows.Next() {
+		var e models.InsuranceFundEntry
+		if err := rows.Scan(&e.Id, &e.OrderId, &e.Reason, &e.Amount, &e.Balance, &e.CreatedAt); err != nil {
+			log.Error("Failed to scan insurance fund entry", "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		history = append(history, e)
+	}
+
+	return history, rows.Err()
+}
+
+// insuranceLines journals fund entries of closed position against counterparty of traders' pnl:
+// fund pays loss user's margin didn't cover and gets surplus
+func insuranceLines(kind models.LedgerEntryKind, entries []models.InsuranceFundEntry) []ledgerLine {
+	var lines []ledgerLine
+	for _, e := range entries {
+		lines = append(lines, transfer(kind, models.AccountExchangePnl, models.AccountInsurance, e.Amount)...)
+	}
+	return lines
+}
+
+// addInsuranceEntries changes fund balance inside tx and writes each change to history
+func addInsuranceEntries(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, entries []models.InsuranceFundEntry) error {
+	now := time.Now()
+	for _, e := range entries {
+		if e.Amount.IsZero() {
+			continue
+		}
+
+		var balance decimal.Decimal
+		err := tx.QueryRow(ctx, `
+            UPDATE insurance_fund
+            SET balance = balance + $1, updated_at = $2
+            WHERE id = $3
+            RETURNING balance`,
+			e.Amount, now, insuranceFundId,
+		).Scan(&balance)
+		if err != nil {
+			return fmt.Errorf("update insurance fund: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+            INSERT INTO insurance_fund_history (order_id, reason, amount, balance, created_at)
+            VALUES ($1, $2, $3, $4, $5)`,
+			orderID, e.Reason, e.Amount, balance, now,
+		)
+		if err != nil {
+			return fmt.Errorf("insert insurance fund history: %w", err)
+		}
+
+		if balance.IsNegative() {
+			slog.Warn("insurance fund is depleted", "balance", balance, "order_id", orderID)
+		}
+	}
+
+	return nil
+}
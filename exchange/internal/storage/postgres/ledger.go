@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"time"
+)
+
+var ErrUnbalancedJournal = errors.New("ledger journal does not sum to zero")
+
+// ledgerLine is one side of journal
+type ledgerLine struct {
+	kind    models.LedgerEntryKind
+	account models.LedgerAccount
+	amount  decimal.Decimal
+}
+
+// transfer moves amount from one account to another, negative amount moves it back
+func transfer(kind models.LedgerEntryKind, from, to models.LedgerAccount, amount decimal.Decimal) []ledgerLine {
+	return []ledgerLine{
+		{kind: kind, account: from, amount: amount.Neg()},
+		{kind: kind, account: to, amount: amount},
+	}
+}
+
+// postJournal writes balanced journal of user's movement inside tx of the movement itself
+func postJournal(ctx context.Context, tx pgx.Tx, userID int64, orderID *uuid.UUID, lines ...ledgerLine) error {
+	sum := decimal.Zero
+	for _, l := range lines {
+		sum = sum.Add(l.amount)
+	}
+	if !sum.IsZero() {
+		return fmt.Errorf("%w: %s", ErrUnbalancedJournal, sum)
+	}
+
+	var journalID int64
+	if err := tx.QueryRow(ctx, `SELECT nextval('ledger_journal_seq')`).Scan(&journalID); err != nil {
+		return fmt.Errorf("next journal id: %w", err)
+	}
+
+	now := time.Now()
+	for _, l := range lines {
+		if l.amount.IsZero() {
+			continue
+		}
+		_, err := tx.Exec(ctx, `
+            INSERT INTO ledger_entries (journal_id, kind, account, user_id, order_id, amount, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			journalID, l.kind, l.account, userID, orderID, l.amount, now,
+		)
+		if err != nil {
+			return fmt.Errorf("insert ledger entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FindLedgerDrifts compares users.balance with user's wallet entries
+// and looks for journals which don't sum to zero
+func (s *Storage) FindLedgerDrifts(ctx context.Context) ([]models.LedgerDrift, error) {
+	const op = "postgresql.FindLedgerDrifts"
+	log := slog.With("op", op)
+
+	const queryBalanceDrifts = `
+        SELECT u.id, u.balance, COALESCE(l.balance, 0)
+        FROM users u
+        LEFT JOIN (SELECT user_id, SUM(amount) AS balance
+                   FROM ledger_entries
+                   WHERE account = $1
+                   GROUP BY user_id) l ON l.user_id = u.id
+        WHERE u.balance <> COALESCE(l.balance, 0)`
+	rows, err := s.db.Query(ctx, queryBalanceDrifts, models.AccountWallet)
+	if err != nil {
+		log.Error("Failed to find balance drifts", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	drifts := []models.LedgerDrift{}
+	for rows.Next() {
+		var (
+			userID int64
+			drift  = models.LedgerDrift{Kind: models.DriftBalance, DetectedAt: now}
+		)
+		if err := rows.Scan(&userID, &drift.Expected, &drift.Actual); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		drift.UserId = &userID
+		drifts = append(drifts, drift)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	const queryJournalDrifts = `
+        SELECT journal_id, SUM(amount)
+        FROM ledger_entries
+        GROUP BY journal_id
+        HAVING SUM(amount) <> 0`
+	rows, err = s.db.Query(ctx, queryJournalDrifts)
+	if err != nil {
+		log.Error("Failed to find journal drifts", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			journalID int64
+			drift     = models.LedgerDrift{Kind: models.DriftJournal, DetectedAt: now}
+		)
+		if err := rows.Scan(&journalID, &drift.Actual); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		drift.JournalId = &journalID
+		drifts = append(drifts, drift)
+	}
+
+	return drifts, rows.Err()
+}
+
+// SaveLedgerDrifts records drifts not recorded yet, drift found again with the same
+// expected and actual values keeps time it was first detected at
+func (s *Storage) SaveLedgerDrifts(ctx context.Context, drifts []models.LedgerDrift) error {
+	const op = "postgresql.SaveLedgerDrifts"
+
+	batch := &pgx.Batch{}
+	for _, d := range drifts {
+		batch.Queue(`
+            INSERT INTO ledger_drifts (kind, user_id, journal_id, expected, actual, detected_at)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            ON CONFLICT ON CONSTRAINT unique_drift DO NOTHING`,
+			d.Kind, d.UserId, d.JournalId, d.Expected, d.Actual, d.DetectedAt,
+		)
+	}
+	if err := s.db.SendBatch(ctx, batch).Close(); err != nil {
+		slog.Error("Failed to save ledger drifts", "op", op, "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetLedgerDrifts returns drifts found by reconciliation, newest first
+func (s *Storage) GetLedgerDrifts(ctx context.Context, limit, offset int) ([]models.LedgerDrift, error) {
+	const op = "postgresql.GetLedgerDrifts"
+	log := slog.With("op", op)
+
+	const queryGetDrifts = `
+        SELECT id, kind, user_id, journal_id, expected, actual, detected_at
+        FROM ledger_drifts ORDER BY id DESC LIMIT $1 OFFSET $2`
+	rows, err := s.db.Query(ctx, queryGetDrifts, limit, offset)
+	if err != nil {
+		log.Error("Failed to get ledger drifts", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	drifts := []models.LedgerDrift{}
+	for rows.Next() {
+		var d models.LedgerDrift
+		if err := rows.Scan(&d.Id, &d.Kind, &d.UserId, &d.JournalId, &d.Expected, &d.Actual, &d.DetectedAt); err != nil {
+			log.Error("Failed to scan ledger drift", "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		drifts = append(drifts, d)
+	}
+
+	return drifts, rows.Err()
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/events"
+	"Exchange/internal/domain/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"log/slog"
+)
+
+// enqueueEvent writes domain event to outbox inside tx of the change it describes,
+// so event is published if and only if the change is committed
+func enqueueEvent(ctx context.Context, tx pgx.Tx, eventType events.Type, userID int64, data any) error {
+	event, err := events.New(eventType, userID, data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", eventType, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO outbox (event_id, subject, payload, created_at)
+        VALUES ($1, $2, $3, $4)`,
+		event.Id, event.Subject(), payload, event.Time,
+	)
+	if err != nil {
+		return fmt.Errorf("insert outbox message: %w", err)
+	}
+	return nil
+}
+
+// enqueueOrderEvent reports order as it is after changes made in tx
+func enqueueOrderEvent(ctx context.Context, tx pgx.Tx, eventType events.Type, orderID uuid.UUID) error {
+	order, err := scanOrder(tx.QueryRow(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, orderID))
+	if err != nil {
+		return fmt.Errorf("get order of %s event: %w", eventType, err)
+	}
+	return enqueueEvent(ctx, tx, eventType, order.UserId, events.OrderOf(order))
+}
+
+// enqueueBalanceEvent reports user's balance as it is after changes made in tx
+func enqueueBalanceEvent(ctx context.Context, tx pgx.Tx, userID int64) error {
+	var balance decimal.Decimal
+	if err := tx.QueryRow(ctx, `SELECT balance FROM users WHERE id = $1`, userID).Scan(&balance); err != nil {
+		return fmt.Errorf("get balance of %s event: %w", events.BalanceChanged, err)
+	}
+	return enqueueEvent(ctx, tx, events.BalanceChanged, userID, events.Balance{Balance: balance})
+}
+
+// RelayOutbox passes up to limit oldest outbox messages to publish and deletes published
+// ones in one transaction. Transaction holds advisory lock, so of several relays only one
+// publishes at a time and messages keep their order, the others relay nothing.
+// Messages published before failed one are deleted too. Returns number of published messages
+func (s *Storage) RelayOutbox(ctx context.Context, limit int, publish func(models.OutboxMessage) error) (int, error) {
+	const op = "postgresql.RelayOutbox"
+	log := slog.With("op", op)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		log.Error("Failed to begin transaction", "err", err)
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtext('outbox'))`).Scan(&locked); err != nil {
+		log.Error("Failed to lock outbox", "err", err)
+		return 0, fmt.Errorf("%s: lock outbox: %w", op, err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+        SELECT id, event_id, subject, payload, created_at
+        FROM outbox ORDER BY id LIMIT $1`, limit)
+	if err != nil {
+		log.Error("Failed to get outbox messages", "err", err)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var m models.OutboxMessage
+		if err := rows.Scan(&m.Id, &m.EventId, &m.Subject, &m.Payload, &m.CreatedAt); err != nil {
+			rows.Close()
+			log.Error("Failed to scan outbox message", "err", err)
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		messages = append(messages, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	published := make([]int64, 0, len(messages))
+	var publishErr error
+	for _, m := range messages {
+		if publishErr = publish(m); publishErr != nil {
+			break
+		}
+		published = append(published, m.Id)
+	}
+
+	if len(published) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, published); err != nil {
+			log.Error("Failed to delete published messages", "err", err)
+			return 0, fmt.Errorf("%s: delete published: %w", op, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			log.Error("Failed to commit transaction", "err", err)
+			return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+		}
+	}
+
+	if publishErr != nil {
+		return len(published), fmt.Errorf("%s: %w", op, publishErr)
+	}
+	return len(published), nil
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"Exchange/internal/domain/events"
 	"Exchange/internal/domain/models"
 	"context"
 	"database/sql"
@@ -12,17 +13,31 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/shopspring/decimal"
 	"log/slog"
+	"slices"
 	"time"
 )
 
 const (
 	uniqueViolation = "23505"
+
+	orderColumns = `id, user_id, pair_id, type, margin, leverage, entry_price, close_price,
+        status, created_at, liquidation_price, ticker, limit_price, stop_loss, take_profit,
+        trailing_type, trailing_value, trailing_extreme, extra_margin, liquidation_fee,
+        open_fee, close_fee, funding`
 )
 
 var (
 	ErrUserAlreadyExists    = errors.New("user already exists")
 	ErrTradingPairNotExists = errors.New("trading pair does not exist")
 	ErrOrderNotExists       = errors.New("order does not exist")
+	ErrOrderNotPending      = errors.New("order is not pending")
+	ErrOrderNotActive       = errors.New("order is not open or pending")
+	ErrOrderNotOpen         = errors.New("order is not open")
+	ErrCloseExceedsMargin   = errors.New("partial close exceeds position margin")
+	ErrOrderChanged         = errors.New("order was changed concurrently")
+	ErrNegativeExtraMargin  = errors.New("extra margin can't be negative")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrNegativeCredit       = errors.New("close can't debit user balance")
 )
 
 type Storage struct {
@@ -117,35 +132,56 @@ func (s *Storage) GetUserById(ctx context.Context, id int64) (models.User, error
 
 func (s *Storage) IncreaseBalance(ctx context.Context, id int64, increaseAmount decimal.Decimal) (decimal.Decimal, error) {
 	const op = "postgresql.IncreaseBalance"
-	log := slog.With("op", op)
-
-	const queryIncreaseBalance = "UPDATE users SET balance = balance + $1 WHERE id = $2 RETURNING balance"
 
-	var updatedBalance decimal.Decimal
-	err := s.db.QueryRow(ctx, queryIncreaseBalance, increaseAmount, id).Scan(&updatedBalance)
+	updatedBalance, err := s.changeBalance(ctx, id, increaseAmount,
+		transfer(models.LedgerDeposit, models.AccountExternal, models.AccountWallet, increaseAmount))
 	if err != nil {
-		log.Error("Failed to increase balance", "id", id, "err", err)
+		slog.Error("Failed to increase balance", "op", op, "id", id, "err", err)
 		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info("balance successfully increased", "id", id, "balance", increaseAmount)
+	slog.Info("balance successfully increased", "op", op, "id", id, "balance", increaseAmount)
 	return updatedBalance, nil
 }
 
 func (s *Storage) DecreaseBalance(ctx context.Context, id int64, decreaseAmount decimal.Decimal) (decimal.Decimal, error) {
 	const op = "postgresql.DecreaseBalance"
-	log := slog.With("op", op)
 
-	const queryIncreaseBalance = "UPDATE users SET balance = balance - $1 WHERE id = $2 RETURNING balance"
+	updatedBalance, err := s.changeBalance(ctx, id, decreaseAmount.Neg(),
+		transfer(models.LedgerWithdrawal, models.AccountWallet, models.AccountExternal, decreaseAmount))
+	if err != nil {
+		slog.Error("Failed to decrease balance", "op", op, "id", id, "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("Balance successfully decreased", "op", op, "id", id, "balance", decreaseAmount)
+	return updatedBalance, nil
+}
+
+// changeBalance adds delta to user balance and journals it in one transaction
+func (s *Storage) changeBalance(ctx context.Context, id int64, delta decimal.Decimal, lines []ledgerLine) (decimal.Decimal, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
 	var updatedBalance decimal.Decimal
-	err := s.db.QueryRow(ctx, queryIncreaseBalance, decreaseAmount, id).Scan(&updatedBalance)
+	err = tx.QueryRow(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2 RETURNING balance", delta, id).
+		Scan(&updatedBalance)
 	if err != nil {
-		log.Error("Failed to decrease balance", "id", id, "err", err)
-		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+		return decimal.Zero, err
+	}
+	if err := postJournal(ctx, tx, id, nil, lines...); err != nil {
+		return decimal.Zero, err
+	}
+	if err := enqueueEvent(ctx, tx, events.BalanceChanged, id, events.Balance{Balance: updatedBalance}); err != nil {
+		return decimal.Zero, err
 	}
 
-	log.Info("Balance successfully decreased", "id", id, "balance", decreaseAmount)
+	if err := tx.Commit(ctx); err != nil {
+		return decimal.Zero, fmt.Errorf("commit transaction: %w", err)
+	}
 	return updatedBalance, nil
 }
 
@@ -179,12 +215,8 @@ func (s *Storage) CreateOrder(ctx context.Context,
 func (s *Storage) GetOrder(ctx context.Context, id uuid.UUID) (models.Order, error) {
 	const op = "postgresql.GetOrder"
 	log := slog.With("op", op)
-	const queryGetOrder = `SELECT * FROM orders WHERE id = $1`
-	var order models.Order
-	err := s.db.QueryRow(ctx, queryGetOrder, id).Scan(
-		&order.Id, &order.UserId, &order.PairId, &order.Type,
-		&order.Margin, &order.Leverage, &order.EntryPrice,
-		&order.ClosePrice, &order.Status, &order.CreatedAt, &order.LiquidationPrice, &order.Ticker)
+	const queryGetOrder = `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	order, err := scanOrder(s.db.QueryRow(ctx, queryGetOrder, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return order, fmt.Errorf("%s: %w", op, ErrOrderNotExists)
@@ -200,7 +232,7 @@ func (s *Storage) GetOrder(ctx context.Context, id uuid.UUID) (models.Order, err
 func (s *Storage) GetUserOrders(ctx context.Context, userId int64) ([]models.Order, error) {
 	const op = "postgresql.GetUserOrders"
 	log := slog.With("op", op)
-	const queryGetUserOrders = `SELECT * FROM orders WHERE user_id = $1`
+	const queryGetUserOrders = `SELECT ` + orderColumns + ` FROM orders WHERE user_id = $1`
 	var orders []models.Order
 	rows, err := s.db.Query(ctx, queryGetUserOrders, userId)
 	if err != nil {
@@ -209,12 +241,7 @@ func (s *Storage) GetUserOrders(ctx context.Context, userId int64) ([]models.Ord
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(&order.Id, &order.UserId,
-			&order.PairId, &order.Type,
-			&order.Margin, &order.Leverage,
-			&order.EntryPrice, &order.ClosePrice,
-			&order.Status, &order.CreatedAt, &order.LiquidationPrice, &order.Ticker)
+		order, err := scanOrder(rows)
 		if err != nil {
 			log.Error("Failed to scan user order", "user_id", userId, "err", err)
 			return orders, fmt.Errorf("%s: %w", op, err)
@@ -226,6 +253,32 @@ func (s *Storage) GetUserOrders(ctx context.Context, userId int64) ([]models.Ord
 	return orders, nil
 }
 
+// GetUserActiveOrders returns user's open positions and pending limit orders
+func (s *Storage) GetUserActiveOrders(ctx context.Context, userId int64) ([]models.Order, error) {
+	const op = "postgresql.GetUserActiveOrders"
+	log := slog.With("op", op)
+
+	const queryGetActive = `SELECT ` + orderColumns + ` FROM orders WHERE user_id = $1 AND status IN ($2, $3) ORDER BY created_at`
+	rows, err := s.db.Query(ctx, queryGetActive, userId, models.Open, models.Pending)
+	if err != nil {
+		log.Error("Failed to get active orders", "user_id", userId, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			log.Error("Failed to scan active order", "user_id", userId, "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
 func (s *Storage) OpenOrder(
 	ctx context.Context,
 	id uuid.UUID,
@@ -239,6 +292,9 @@ func (s *Storage) OpenOrder(
 	createdAt time.Time,
 	liquidationPrice decimal.Decimal,
 	ticker string,
+	limitPrice *decimal.Decimal,
+	stops models.Stops,
+	openFee models.OrderFee,
 ) (orderID uuid.UUID, err error) {
 	const op = "postgresql.OpenOrder"
 	log := slog.With("op", op)
@@ -258,20 +314,33 @@ func (s *Storage) OpenOrder(
 	// 1. Создаем ордер
 	const queryCreateOrder = `
         INSERT INTO orders(id, user_id, pair_id, type, margin, leverage, 
-                          entry_price, status, created_at, liquidation_price, ticker)
-        VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+                          entry_price, status, created_at, liquidation_price, ticker, limit_price,
+                          stop_loss, take_profit, open_fee, opened_at)
+        VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
         RETURNING id`
 
+	// pending limit order is opened once filled
+	var openedAt *time.Time
+	if status == models.Open {
+		openedAt = &createdAt
+	}
 	err = tx.QueryRow(ctx, queryCreateOrder,
 		id, userId, pairId, orderType, margin,
-		leverage, entryPrice, status, createdAt, liquidationPrice, ticker,
+		leverage, entryPrice, status, createdAt, liquidationPrice, ticker, limitPrice,
+		stops.StopLoss, stops.TakeProfit, openFee.Amount, openedAt,
 	).Scan(&orderID)
 	if err != nil {
 		log.Error("Failed to open order", "err", err)
 		return uuid.Nil, fmt.Errorf("%s: create order: %w", op, err)
 	}
 
-	// 2. Списываем средства
+	// 2. Записываем комиссию за открытие
+	if err = insertOrderFee(ctx, tx, orderID, userId, openFee, createdAt); err != nil {
+		log.Error("Failed to record open fee", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 3. Списываем маржу и комиссию
 	const queryDecreaseBalance = `
         UPDATE users 
         SET balance = balance - $1 
@@ -279,7 +348,7 @@ func (s *Storage) OpenOrder(
         RETURNING balance`
 
 	var newBalance decimal.Decimal
-	err = tx.QueryRow(ctx, queryDecreaseBalance, margin, userId).Scan(&newBalance)
+	err = tx.QueryRow(ctx, queryDecreaseBalance, margin.Add(openFee.Amount), userId).Scan(&newBalance)
 	if err != nil {
 		log.Error("Failed to decrease balance", "err", err)
 		return uuid.Nil, fmt.Errorf("%s: decrease balance: %w", op, err)
@@ -291,7 +360,27 @@ func (s *Storage) OpenOrder(
 		return uuid.Nil, fmt.Errorf("%s: insufficient funds", op)
 	}
 
-	// 3. Фиксируем транзакцию
+	err = postJournal(ctx, tx, userId, &orderID, append(
+		transfer(models.LedgerMarginLock, models.AccountWallet, models.AccountMargin, margin),
+		transfer(models.LedgerFee, models.AccountWallet, models.AccountFees, openFee.Amount)...)...)
+	if err != nil {
+		log.Error("Failed to journal order opening", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if status == models.Open {
+		if err = enqueueOrderEvent(ctx, tx, events.OrderOpened, orderID); err != nil {
+			log.Error("Failed to enqueue order event", "err", err)
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	err = enqueueEvent(ctx, tx, events.BalanceChanged, userId, events.Balance{Balance: newBalance})
+	if err != nil {
+		log.Error("Failed to enqueue balance event", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 4. Фиксируем транзакцию
 	if err = tx.Commit(ctx); err != nil {
 		log.Error("Failed to commit transaction", "err", err)
 		return uuid.Nil, fmt.Errorf("%s: commit transaction: %w", op, err)
@@ -304,30 +393,333 @@ func (s *Storage) OpenOrder(
 	return orderID, nil
 }
 
-func (s *Storage) LiquidateOrder(ctx context.Context, orderID uuid.UUID, closePrice decimal.Decimal) (uuid.UUID, error) {
-	const op = "postgres.LiquidateOrder"
+// FillOrder turns pending limit order into open position. Margin was already
+// reserved by OpenOrder when the limit order was placed
+func (s *Storage) FillOrder(ctx context.Context,
+	orderID uuid.UUID,
+	entryPrice decimal.Decimal,
+	liquidationPrice decimal.Decimal,
+) (uuid.UUID, error) {
+	const op = "postgresql.FillOrder"
+	log := slog.With("op", op, "order_id", orderID)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		log.Error("Failed to begin transaction", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	const queryFillOrder = `
+        UPDATE orders
+        SET status = $1, entry_price = $2, liquidation_price = $3, opened_at = $4
+        WHERE id = $5 AND status = $6
+        RETURNING id`
+
+	var filledId uuid.UUID
+	err = tx.QueryRow(ctx, queryFillOrder,
+		models.Open, entryPrice, liquidationPrice, time.Now(), orderID, models.Pending,
+	).Scan(&filledId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Info("Order is not pending anymore")
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrderNotPending)
+		}
+		log.Error("Failed to fill order", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := enqueueOrderEvent(ctx, tx, events.OrderOpened, filledId); err != nil {
+		log.Error("Failed to enqueue order event", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error("Failed to commit transaction", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	log.Info("Order successfully filled", "entry_price", entryPrice)
+	return filledId, nil
+}
+
+// CancelOrder sets pending order status to 'canceled' and returns reserved margin and open fee
+func (s *Storage) CancelOrder(ctx context.Context, orderID uuid.UUID) (uuid.UUID, error) {
+	const op = "postgresql.CancelOrder"
+	log := slog.With("op", op, "order_id", orderID)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		log.Error("Failed to begin transaction", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		userID  int64
+		status  models.OrderStatus
+		margin  decimal.Decimal
+		openFee decimal.Decimal
+	)
+	err = tx.QueryRow(ctx, `
+        SELECT user_id, status, margin, open_fee
+        FROM orders
+        WHERE id = $1
+        FOR UPDATE`,
+		orderID,
+	).Scan(&userID, &status, &margin, &openFee)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrderNotExists)
+	}
+	if err != nil {
+		log.Error("Failed to get order", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: get order: %w", op, err)
+	}
+
+	if status != models.Pending {
+		log.Error("Order is not pending", "status", status)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrderNotPending)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE orders SET status = $1, open_fee = 0 WHERE id = $2`, models.Canceled, orderID)
+	if err != nil {
+		log.Error("Failed to cancel order", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: cancel order: %w", op, err)
+	}
+
+	// order was never traded, its fee is refunded and does not count as volume
+	_, err = tx.Exec(ctx, `DELETE FROM order_fees WHERE order_id = $1`, orderID)
+	if err != nil {
+		log.Error("Failed to delete order fees", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: delete fees: %w", op, err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET balance = balance + $1 WHERE id = $2`, margin.Add(openFee), userID)
+	if err != nil {
+		log.Error("Failed to return margin", "user_id", userID, "err", err)
+		return uuid.Nil, fmt.Errorf("%s: return margin: %w", op, err)
+	}
+
+	err = postJournal(ctx, tx, userID, &orderID, append(
+		transfer(models.LedgerMarginRelease, models.AccountMargin, models.AccountWallet, margin),
+		transfer(models.LedgerFee, models.AccountFees, models.AccountWallet, openFee)...)...)
+	if err != nil {
+		log.Error("Failed to journal order cancel", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := enqueueBalanceEvent(ctx, tx, userID); err != nil {
+		log.Error("Failed to enqueue balance event", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error("Failed to commit transaction", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	log.Info("Order successfully canceled", "user_id", userID, "margin", margin, "open_fee", openFee)
+	return orderID, nil
+}
+
+// UpdateOrderStops replaces stop-loss and take-profit of open or pending order
+func (s *Storage) UpdateOrderStops(ctx context.Context, orderID uuid.UUID, stops models.Stops) (uuid.UUID, error) {
+	const op = "postgresql.UpdateOrderStops"
+	log := slog.With("op", op, "order_id", orderID)
+
+	const queryUpdateStops = `
+        UPDATE orders
+        SET stop_loss = $1, take_profit = $2
+        WHERE id = $3 AND status IN ($4, $5)
+        RETURNING id`
+
+	var updatedId uuid.UUID
+	err := s.db.QueryRow(ctx, queryUpdateStops,
+		stops.StopLoss, stops.TakeProfit, orderID, models.Open, models.Pending,
+	).Scan(&updatedId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Info("Order is not active")
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrderNotActive)
+		}
+		log.Error("Failed to update order stops", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("Order stops updated", "stop_loss", stops.StopLoss, "take_profit", stops.TakeProfit)
+	return updatedId, nil
+}
+
+// SetTrailingStop attaches trailing stop to open order, nil removes it
+func (s *Storage) SetTrailingStop(ctx context.Context, orderID uuid.UUID, ts *models.TrailingStop) (uuid.UUID, error) {
+	const op = "postgresql.SetTrailingStop"
+	log := slog.With("op", op, "order_id", orderID)
+
+	var (
+		trailingType    *models.TrailingType
+		trailingValue   *decimal.Decimal
+		trailingExtreme *decimal.Decimal
+	)
+	if ts != nil {
+		trailingType, trailingValue, trailingExtreme = &ts.Type, &ts.Value, &ts.Extreme
+	}
+
+	const querySetTrailing = `
+        UPDATE orders
+        SET trailing_type = $1, trailing_value = $2, trailing_extreme = $3
+        WHERE id = $4 AND status = $5
+        RETURNING id`
+
+	var updatedId uuid.UUID
+	err := s.db.QueryRow(ctx, querySetTrailing,
+		trailingType, trailingValue, trailingExtreme, orderID, models.Open,
+	).Scan(&updatedId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrderNotActive)
+		}
+		log.Error("Failed to set trailing stop", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("Trailing stop set", "trailing_stop", ts)
+	return updatedId, nil
+}
+
+// UpdateTrailingExtreme moves best price of trailing stop
+func (s *Storage) UpdateTrailingExtreme(ctx context.Context, orderID uuid.UUID, extreme decimal.Decimal) error {
+	const op = "postgresql.UpdateTrailingExtreme"
+
+	const queryUpdateExtreme = `
+        UPDATE orders SET trailing_extreme = $1
+        WHERE id = $2 AND status = $3 AND trailing_type IS NOT NULL`
+
+	if _, err := s.db.Exec(ctx, queryUpdateExtreme, extreme, orderID, models.Open); err != nil {
+		slog.Error("Failed to update trailing extreme", "op", op, "order_id", orderID, "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetOpenTrailingOrders returns open orders with trailing stop
+func (s *Storage) GetOpenTrailingOrders(ctx context.Context) ([]models.Order, error) {
+	const op = "postgresql.GetOpenTrailingOrders"
 	log := slog.With("op", op)
-	const queryLiquidateOrder = "UPDATE orders SET close_price=$1, status='liquidated' WHERE id=$2 RETURNING id"
-	var liqOrderId uuid.UUID
-	err := s.db.QueryRow(ctx, queryLiquidateOrder, closePrice, orderID).Scan(&liqOrderId)
+
+	const queryGetTrailing = `SELECT ` + orderColumns + ` FROM orders WHERE status = $1 AND trailing_type IS NOT NULL`
+	rows, err := s.db.Query(ctx, queryGetTrailing, models.Open)
+	if err != nil {
+		log.Error("Failed to get trailing orders", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			log.Error("Failed to scan trailing order", "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// LiquidateOrder sets order status to 'liquidated', records liquidation fee
+// and settles position with insurance fund
+func (s *Storage) LiquidateOrder(
+	ctx context.Context,
+	orderID uuid.UUID,
+	closePrice decimal.Decimal,
+	liquidationFee decimal.Decimal,
+	fundEntries []models.InsuranceFundEntry,
+) (uuid.UUID, error) {
+	const op = "postgres.LiquidateOrder"
+	log := slog.With("op", op, "order_id", orderID)
+
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		log.Error("Failed to liquidate order", "order_id", orderID, "err", err)
+		log.Error("Failed to begin transaction", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		userID       int64
+		lockedMargin decimal.Decimal
+	)
+	err = tx.QueryRow(ctx, `
+        UPDATE orders
+        SET status = $1, close_price = $2, liquidation_fee = $3, closed_at = $4
+        WHERE id = $5 AND status = $6
+        RETURNING user_id, margin + extra_margin`,
+		models.Liquidated,
+		closePrice,
+		liquidationFee,
+		time.Now(),
+		orderID,
+		models.Open,
+	).Scan(&userID, &lockedMargin)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrderNotOpen)
+	}
+	if err != nil {
+		log.Error("Failed to liquidate order", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := addInsuranceEntries(ctx, tx, orderID, fundEntries); err != nil {
+		log.Error("Failed to settle with insurance fund", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// locked margin is lost: equity left goes to insurance fund, the rest to counterparty
+	lines := []ledgerLine{{kind: models.LedgerLiquidation, account: models.AccountMargin, amount: lockedMargin.Neg()}}
+	equity := decimal.Zero
+	for _, e := range fundEntries {
+		equity = equity.Add(e.Amount)
+		lines = append(lines, ledgerLine{kind: models.LedgerLiquidation, account: models.AccountInsurance, amount: e.Amount})
+	}
+	lines = append(lines, ledgerLine{kind: models.LedgerLiquidation, account: models.AccountExchangePnl, amount: lockedMargin.Sub(equity)})
+	if err := postJournal(ctx, tx, userID, &orderID, lines...); err != nil {
+		log.Error("Failed to journal liquidation", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := enqueueOrderEvent(ctx, tx, events.OrderLiquidated, orderID); err != nil {
+		log.Error("Failed to enqueue order event", "err", err)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info("Successfully liquidated order", "order_id", orderID)
-	return liqOrderId, nil
+	if err := tx.Commit(ctx); err != nil {
+		log.Error("Failed to commit transaction", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	log.Info("Successfully liquidated order",
+		"user_id", userID,
+		"liquidation_fee", liquidationFee)
+	return orderID, nil
 }
 
-// CloseOrder sets order status to 'closed' and change order owner funds
+// CloseOrder sets order status to 'closed' and change order owner funds.
+// Loss past locked margin is booked to insurance fund by fundEntries, wallet is never debited
 func (s *Storage) CloseOrder(
 	ctx context.Context,
 	orderID uuid.UUID,
 	closePrice decimal.Decimal,
 	balanceIncrease decimal.Decimal,
+	closeFee models.OrderFee,
+	fundEntries []models.InsuranceFundEntry,
 ) (uuid.UUID, error) {
 	const op = "postgresql.CloseOrder"
 	log := slog.With("op", op, "order_id", orderID)
+	// ledger keeps cents, balance has to change by the same amount
+	balanceIncrease = balanceIncrease.Round(2)
+	if balanceIncrease.IsNegative() {
+		return uuid.Nil, fmt.Errorf("%s: %w: %s", op, ErrNegativeCredit, balanceIncrease)
+	}
 
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
@@ -338,20 +730,20 @@ func (s *Storage) CloseOrder(
 
 	// 1. Получаем данные ордера и блокируем его для изменения
 	var (
-		userID int64
-		status models.OrderStatus
+		userID       int64
+		status       models.OrderStatus
+		lockedMargin decimal.Decimal
 	)
 	err = tx.QueryRow(ctx, `
-        SELECT user_id, status 
+        SELECT user_id, status, margin + extra_margin
         FROM orders 
         WHERE id = $1 
         FOR UPDATE`, // Блокировка ордера
 		orderID,
-	).Scan(&userID, &status)
+	).Scan(&userID, &status, &lockedMargin)
 
 	if errors.Is(err, pgx.ErrNoRows) {
-		log.Error("Order not found")
-		return uuid.Nil, fmt.Errorf("%s: order not found", op)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrderNotExists)
 	}
 	if err != nil {
 		log.Error("Failed to get order", "err", err)
@@ -360,8 +752,7 @@ func (s *Storage) CloseOrder(
 
 	// 2. Проверяем, что ордер можно закрыть
 	if status != models.Open {
-		log.Error("Order is not open", "status", status)
-		return uuid.Nil, fmt.Errorf("%s: order is not open", op)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrderNotOpen)
 	}
 
 	// 3. Обновляем ордер (закрываем)
@@ -369,16 +760,24 @@ func (s *Storage) CloseOrder(
         UPDATE orders 
         SET 
             status = $1,
-            close_price = $2
-        WHERE id = $3`,
+            close_price = $2,
+            close_fee = close_fee + $3,
+            closed_at = $4
+        WHERE id = $5`,
 		models.Closed,
 		closePrice,
+		closeFee.Amount,
+		time.Now(),
 		orderID,
 	)
 	if err != nil {
 		log.Error("Failed to close order", "err", err)
 		return uuid.Nil, fmt.Errorf("%s: close order: %w", op, err)
 	}
+	if err := insertOrderFee(ctx, tx, orderID, userID, closeFee, time.Now()); err != nil {
+		log.Error("Failed to record close fee", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	// 4. Увеличиваем баланс пользователя на указанную сумму
 	var newBalance decimal.Decimal
@@ -395,7 +794,34 @@ func (s *Storage) CloseOrder(
 		return uuid.Nil, fmt.Errorf("%s: increase balance: %w", op, err)
 	}
 
-	// 5. Фиксируем транзакцию
+	if err := addInsuranceEntries(ctx, tx, orderID, fundEntries); err != nil {
+		log.Error("Failed to settle with insurance fund", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 5. Записываем в журнал: возврат маржи, прибыль, комиссию и покрытие убытка фондом
+	pnl := balanceIncrease.Add(closeFee.Amount).Sub(lockedMargin)
+	err = postJournal(ctx, tx, userID, &orderID, slices.Concat(
+		transfer(models.LedgerMarginRelease, models.AccountMargin, models.AccountWallet, lockedMargin),
+		transfer(models.LedgerRealizedPnl, models.AccountExchangePnl, models.AccountWallet, pnl),
+		transfer(models.LedgerFee, models.AccountWallet, models.AccountFees, closeFee.Amount),
+		insuranceLines(models.LedgerRealizedPnl, fundEntries),
+	)...)
+	if err != nil {
+		log.Error("Failed to journal order close", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := enqueueOrderEvent(ctx, tx, events.OrderClosed, orderID); err != nil {
+		log.Error("Failed to enqueue order event", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	err = enqueueEvent(ctx, tx, events.BalanceChanged, userID, events.Balance{Balance: newBalance})
+	if err != nil {
+		log.Error("Failed to enqueue balance event", "err", err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 6. Фиксируем транзакцию
 	if err := tx.Commit(ctx); err != nil {
 		log.Error("Failed to commit transaction", "err", err)
 		return uuid.Nil, fmt.Errorf("%s: commit transaction: %w", op, err)
@@ -408,6 +834,274 @@ func (s *Storage) CloseOrder(
 	return orderID, nil
 }
 
+// PartialCloseOrder releases part of position margin, sets liquidation price of the rest,
+// records the close and returns margin plus realized pnl to order owner. Loss past
+// released margin is booked to insurance fund by fundEntries, wallet is never debited.
+// expectedMargin and expectedExtraMargin guard against concurrent changes of order
+// the close and liquidation price were computed from
+func (s *Storage) PartialCloseOrder(
+	ctx context.Context,
+	orderID uuid.UUID,
+	expectedMargin decimal.Decimal,
+	expectedExtraMargin decimal.Decimal,
+	closedMargin decimal.Decimal,
+	closedExtraMargin decimal.Decimal,
+	closePrice decimal.Decimal,
+	realizedPnl decimal.Decimal,
+	closeFee models.OrderFee,
+	fundEntries []models.InsuranceFundEntry,
+	liquidationPrice decimal.Decimal,
+) (decimal.Decimal, error) {
+	const op = "postgresql.PartialCloseOrder"
+	log := slog.With("op", op, "order_id", orderID)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		log.Error("Failed to begin transaction", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Блокируем ордер
+	var (
+		userID      int64
+		status      models.OrderStatus
+		margin      decimal.Decimal
+		extraMargin decimal.Decimal
+	)
+	err = tx.QueryRow(ctx, `
+        SELECT user_id, status, margin, extra_margin
+        FROM orders
+        WHERE id = $1
+        FOR UPDATE`,
+		orderID,
+	).Scan(&userID, &status, &margin, &extraMargin)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrOrderNotExists)
+	}
+	if err != nil {
+		log.Error("Failed to get order", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: get order: %w", op, err)
+	}
+
+	// 2. Проверяем, что после закрытия часть позиции остается
+	if status != models.Open {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrOrderNotOpen)
+	}
+	if closedMargin.GreaterThanOrEqual(margin) {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrCloseExceedsMargin)
+	}
+	// liquidation price of the rest is computed from margin the close was priced with
+	if !margin.Equal(expectedMargin) || !extraMargin.Equal(expectedExtraMargin) {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrOrderChanged)
+	}
+
+	// 3. Уменьшаем маржу позиции и пересчитываем цену ликвидации
+	var remainingMargin decimal.Decimal
+	err = tx.QueryRow(ctx, `
+        UPDATE orders
+        SET margin = margin - $1, extra_margin = GREATEST(extra_margin - $2, 0), close_fee = close_fee + $3,
+            liquidation_price = $4
+        WHERE id = $5
+        RETURNING margin`,
+		closedMargin, closedExtraMargin, closeFee.Amount, liquidationPrice, orderID,
+	).Scan(&remainingMargin)
+	if err != nil {
+		log.Error("Failed to decrease order margin", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: decrease margin: %w", op, err)
+	}
+
+	// 4. Записываем частичное закрытие и комиссию
+	now := time.Now()
+	_, err = tx.Exec(ctx, `
+        INSERT INTO order_closes(order_id, margin, close_price, realized_pnl, fee, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)`,
+		orderID, closedMargin.Add(closedExtraMargin), closePrice, realizedPnl, closeFee.Amount, now,
+	)
+	if err != nil {
+		log.Error("Failed to record partial close", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: record close: %w", op, err)
+	}
+	if err := insertOrderFee(ctx, tx, orderID, userID, closeFee, now); err != nil {
+		log.Error("Failed to record close fee", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 5. Возвращаем маржу и прибыль за вычетом комиссии пользователю
+	credit := closedMargin.Add(closedExtraMargin).Add(realizedPnl).Sub(closeFee.Amount)
+	if credit.IsNegative() {
+		return decimal.Zero, fmt.Errorf("%s: %w: %s", op, ErrNegativeCredit, credit)
+	}
+	_, err = tx.Exec(ctx, `UPDATE users SET balance = balance + $1 WHERE id = $2`, credit, userID)
+	if err != nil {
+		log.Error("Failed to increase user balance", "user_id", userID, "err", err)
+		return decimal.Zero, fmt.Errorf("%s: increase balance: %w", op, err)
+	}
+	if err := addInsuranceEntries(ctx, tx, orderID, fundEntries); err != nil {
+		log.Error("Failed to settle with insurance fund", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = postJournal(ctx, tx, userID, &orderID, slices.Concat(
+		transfer(models.LedgerMarginRelease, models.AccountMargin, models.AccountWallet, closedMargin.Add(closedExtraMargin)),
+		transfer(models.LedgerRealizedPnl, models.AccountExchangePnl, models.AccountWallet, realizedPnl),
+		transfer(models.LedgerFee, models.AccountWallet, models.AccountFees, closeFee.Amount),
+		insuranceLines(models.LedgerRealizedPnl, fundEntries),
+	)...)
+	if err != nil {
+		log.Error("Failed to journal partial close", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := enqueueOrderEvent(ctx, tx, events.OrderChanged, orderID); err != nil {
+		log.Error("Failed to enqueue order event", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := enqueueBalanceEvent(ctx, tx, userID); err != nil {
+		log.Error("Failed to enqueue balance event", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error("Failed to commit transaction", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	log.Info("Order partially closed",
+		"user_id", userID,
+		"closed_margin", closedMargin,
+		"realized_pnl", realizedPnl,
+		"remaining_margin", remainingMargin)
+	return remainingMargin, nil
+}
+
+// AdjustMargin moves delta (positive - add, negative - withdraw) between user balance
+// and isolated extra margin of open order and sets new liquidation price.
+// expectedExtraMargin guards against concurrent adjustments computed from stale order
+func (s *Storage) AdjustMargin(
+	ctx context.Context,
+	orderID uuid.UUID,
+	expectedExtraMargin decimal.Decimal,
+	delta decimal.Decimal,
+	liquidationPrice decimal.Decimal,
+) (decimal.Decimal, error) {
+	const op = "postgresql.AdjustMargin"
+	log := slog.With("op", op, "order_id", orderID)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		log.Error("Failed to begin transaction", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Блокируем ордер
+	var (
+		userID      int64
+		status      models.OrderStatus
+		extraMargin decimal.Decimal
+	)
+	err = tx.QueryRow(ctx, `
+        SELECT user_id, status, extra_margin
+        FROM orders
+        WHERE id = $1
+        FOR UPDATE`,
+		orderID,
+	).Scan(&userID, &status, &extraMargin)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrOrderNotExists)
+	}
+	if err != nil {
+		log.Error("Failed to get order", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: get order: %w", op, err)
+	}
+
+	if status != models.Open {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrOrderNotOpen)
+	}
+	if !extraMargin.Equal(expectedExtraMargin) {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrOrderChanged)
+	}
+	newExtraMargin := extraMargin.Add(delta)
+	if newExtraMargin.IsNegative() {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrNegativeExtraMargin)
+	}
+
+	// 2. Обновляем маржу и цену ликвидации
+	_, err = tx.Exec(ctx, `UPDATE orders SET extra_margin = $1, liquidation_price = $2 WHERE id = $3`,
+		newExtraMargin, liquidationPrice, orderID,
+	)
+	if err != nil {
+		log.Error("Failed to update order margin", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: update margin: %w", op, err)
+	}
+
+	// 3. Меняем баланс пользователя
+	var newBalance decimal.Decimal
+	err = tx.QueryRow(ctx, `UPDATE users SET balance = balance - $1 WHERE id = $2 RETURNING balance`,
+		delta, userID,
+	).Scan(&newBalance)
+	if err != nil {
+		log.Error("Failed to update user balance", "user_id", userID, "err", err)
+		return decimal.Zero, fmt.Errorf("%s: update balance: %w", op, err)
+	}
+	if newBalance.IsNegative() {
+		return decimal.Zero, fmt.Errorf("%s: %w", op, ErrInsufficientFunds)
+	}
+
+	lines := transfer(models.LedgerMarginLock, models.AccountWallet, models.AccountMargin, delta)
+	if delta.IsNegative() {
+		lines = transfer(models.LedgerMarginRelease, models.AccountMargin, models.AccountWallet, delta.Neg())
+	}
+	if err := postJournal(ctx, tx, userID, &orderID, lines...); err != nil {
+		log.Error("Failed to journal margin adjustment", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+	err = enqueueEvent(ctx, tx, events.BalanceChanged, userID, events.Balance{Balance: newBalance})
+	if err != nil {
+		log.Error("Failed to enqueue balance event", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error("Failed to commit transaction", "err", err)
+		return decimal.Zero, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	log.Info("Order margin adjusted",
+		"user_id", userID,
+		"delta", delta,
+		"extra_margin", newExtraMargin,
+		"liquidation_price", liquidationPrice)
+	return newExtraMargin, nil
+}
+
+func (s *Storage) GetOrderCloses(ctx context.Context, orderID uuid.UUID) ([]models.OrderClose, error) {
+	const op = "postgresql.GetOrderCloses"
+	log := slog.With("op", op)
+
+	const queryGetCloses = `
+        SELECT id, order_id, margin, close_price, realized_pnl, fee, created_at
+        FROM order_closes WHERE order_id = $1 ORDER BY id`
+	rows, err := s.db.Query(ctx, queryGetCloses, orderID)
+	if err != nil {
+		log.Error("Failed to get order closes", "order_id", orderID, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	closes := []models.OrderClose{}
+	for rows.Next() {
+		var c models.OrderClose
+		if err := rows.Scan(&c.Id, &c.OrderId, &c.Margin, &c.ClosePrice, &c.RealizedPnl, &c.Fee, &c.CreatedAt); err != nil {
+			log.Error("Failed to scan order close", "order_id", orderID, "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		closes = append(closes, c)
+	}
+
+	return closes, rows.Err()
+}
+
 func (s *Storage) AddTradingPair(baseAsset, quoteAsset string) (int64, error) {
 	const op = "postgresql.AddTradingPair"
 	log := slog.With("op", op)
@@ -448,7 +1142,8 @@ func (s *Storage) GetLiqOrders(ctx context.Context, markPrice decimal.Decimal, p
 	const op = "postgresql.GetLiqOrders"
 	log := slog.With("op", op)
 
-	const queryGetLiqOrders = "SELECT id FROM orders WHERE orders.pair_id = :pairId AND ((orders.type = 'long' AND liquidation_price >= :markPrice) AND (orders.type = 'short' AND liquidation_price <= :markPrice))"
+	const queryGetLiqOrders = `SELECT id FROM orders WHERE status = 'open' AND pair_id = $2
+        AND ((type = 'long' AND liquidation_price >= $1) OR (type = 'short' AND liquidation_price <= $1))`
 
 	var orderIds []uuid.UUID
 	rows, err := s.db.Query(context.Background(), queryGetLiqOrders, markPrice, pairId)
@@ -463,19 +1158,39 @@ func (s *Storage) GetLiqOrders(ctx context.Context, markPrice decimal.Decimal, p
 
 	defer rows.Close()
 	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(&order.Id, &order.UserId,
-			&order.PairId, &order.Type,
-			&order.Margin, &order.Leverage,
-			&order.EntryPrice, &order.ClosePrice,
-			&order.Status, &order.CreatedAt, &order.LiquidationPrice, &order.Ticker)
-		if err != nil {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
 			log.Error("Failed to scan orders", "err", err)
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
-		orderIds = append(orderIds, order.Id)
+		orderIds = append(orderIds, id)
 	}
 
 	log.Info("Successfully get orders for liq", "orders", orderIds)
 	return orderIds, nil
 }
+
+func scanOrder(row pgx.Row) (models.Order, error) {
+	var (
+		order           models.Order
+		trailingType    *models.TrailingType
+		trailingValue   *decimal.Decimal
+		trailingExtreme *decimal.Decimal
+	)
+	err := row.Scan(&order.Id, &order.UserId,
+		&order.PairId, &order.Type,
+		&order.Margin, &order.Leverage,
+		&order.EntryPrice, &order.ClosePrice,
+		&order.Status, &order.CreatedAt, &order.LiquidationPrice, &order.Ticker, &order.LimitPrice,
+		&order.StopLoss, &order.TakeProfit,
+		&trailingType, &trailingValue, &trailingExtreme, &order.ExtraMargin, &order.LiquidationFee,
+		&order.OpenFee, &order.CloseFee, &order.Funding)
+	if err == nil && trailingType != nil && trailingValue != nil && trailingExtreme != nil {
+		order.TrailingStop = &models.TrailingStop{
+			Type:    *trailingType,
+			Value:   *trailingValue,
+			Extreme: *trailingExtreme,
+		}
+	}
+	return order, err
+}
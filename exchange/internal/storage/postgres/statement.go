@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"time"
+)
+
+// GetUserTransactions returns page of user's wallet movements, newest first.
+// Balance is running sum over all wallet entries of user, so it doesn't depend on filter
+func (s *Storage) GetUserTransactions(ctx context.Context, userId int64, filter models.TransactionFilter, limit, offset int) ([]models.Transaction, error) {
+	const op = "postgresql.GetUserTransactions"
+	log := slog.With("op", op)
+
+	kinds := make([]string, 0, len(filter.Kinds))
+	for _, kind := range filter.Kinds {
+		kinds = append(kinds, string(kind))
+	}
+
+	const queryGetTransactions = `
+        SELECT id, journal_id, kind, order_id, amount, balance, created_at
+        FROM (SELECT id, journal_id, kind, order_id, amount, created_at,
+                     SUM(amount) OVER (ORDER BY id) AS balance
+              FROM ledger_entries
+              WHERE account = $1 AND user_id = $2) t
+        WHERE (cardinality($3::text[]) = 0 OR kind = ANY($3::text[]))
+          AND ($4::timestamptz IS NULL OR created_at >= $4)
+          AND ($5::timestamptz IS NULL OR created_at < $5)
+        ORDER BY id DESC LIMIT $6 OFFSET $7`
+	rows, err := s.db.Query(ctx, queryGetTransactions,
+		models.AccountWallet, userId, kinds, nullTime(filter.From), nullTime(filter.To), limit, offset)
+	if err != nil {
+		log.Error("Failed to get transactions", "user_id", userId, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		var t models.Transaction
+		err := rows.Scan(&t.Id, &t.JournalId, &t.Kind, &t.OrderId, &t.Amount, &t.Balance, &t.CreatedAt)
+		if err != nil {
+			log.Error("Failed to scan transaction", "user_id", userId, "err", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// GetUserStatement sums user's ledger entries over [from, to).
+// Opening balance entries written when ledger was introduced count as opening balance
+func (s *Storage) GetUserStatement(ctx context.Context, userId int64, from, to time.Time) (models.Statement, error) {
+	const op = "postgresql.GetUserStatement"
+	log := slog.With("op", op)
+
+	const queryGetStatement = `
+        SELECT account, kind, created_at < $2 AS before, SUM(amount)
+        FROM ledger_entries
+        WHERE user_id = $1 AND account IN ($4, $5) AND created_at < $3
+        GROUP BY account, kind, before`
+	rows, err := s.db.Query(ctx, queryGetStatement, userId, from, to, models.AccountWallet, models.AccountMargin)
+	if err != nil {
+		log.Error("Failed to get statement", "user_id", userId, "err", err)
+		return models.Statement{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	statement := models.Statement{From: from, To: to}
+	for rows.Next() {
+		var (
+			account models.LedgerAccount
+			kind    models.LedgerEntryKind
+			before  bool
+			amount  decimal.Decimal
+		)
+		if err := rows.Scan(&account, &kind, &before, &amount); err != nil {
+			log.Error("Failed to scan statement row", "user_id", userId, "err", err)
+			return models.Statement{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if account == models.AccountMargin {
+			if kind == models.LedgerLiquidation && !before {
+				statement.LiquidationLoss = statement.LiquidationLoss.Add(amount.Neg())
+			}
+			continue
+		}
+
+		statement.ClosingBalance = statement.ClosingBalance.Add(amount)
+		if before || kind == models.LedgerOpeningBalance {
+			statement.OpeningBalance = statement.OpeningBalance.Add(amount)
+			continue
+		}
+
+		switch kind {
+		case models.LedgerDeposit:
+			statement.Deposits = statement.Deposits.Add(amount)
+		case models.LedgerWithdrawal:
+			statement.Withdrawals = statement.Withdrawals.Add(amount.Neg())
+		case models.LedgerRealizedPnl:
+			statement.RealizedPnl = statement.RealizedPnl.Add(amount)
+		case models.LedgerFee:
+			statement.Fees = statement.Fees.Add(amount.Neg())
+		case models.LedgerFunding:
+			statement.Funding = statement.Funding.Add(amount)
+		case models.LedgerMarginLock:
+			statement.MarginLocked = statement.MarginLocked.Add(amount.Neg())
+		case models.LedgerMarginRelease:
+			statement.MarginReleased = statement.MarginReleased.Add(amount)
+		}
+	}
+
+	return statement, rows.Err()
+}
+
+// nullTime maps zero time to NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
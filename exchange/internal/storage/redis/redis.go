@@ -5,6 +5,7 @@ import (
 	"Exchange/internal/domain/models"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
@@ -15,10 +16,15 @@ import (
 )
 
 const (
-	prefix      = "exchange:binance:price"
-	orderPrefix = "orders:"
+	prefix             = "exchange:binance:price"
+	indexPrefix        = "exchange:index:price"
+	markPrefix         = "exchange:mark:price"
+	orderPrefix        = "orders:"
+	pendingOrderPrefix = "orders:pending:"
 )
 
+var ErrPriceNotFound = errors.New("price not found")
+
 type Redis struct {
 	client *redis.Client
 }
@@ -74,12 +80,26 @@ func (s *Redis) RemoveOrder(ctx context.Context, id, ticker string, orderType mo
 }
 
 func (s *Redis) SavePrices(ctx context.Context, prices []models.PriceResponse) error {
-	log := slog.With("method", "SavePrices")
+	return s.savePrices(ctx, "SavePrices", prefix, prices)
+}
+
+// SaveIndexPrices stores index prices aggregated across exchanges
+func (s *Redis) SaveIndexPrices(ctx context.Context, prices []models.PriceResponse) error {
+	return s.savePrices(ctx, "SaveIndexPrices", indexPrefix, prices)
+}
+
+// SaveMarkPrices stores mark prices which trigger liquidations and stops
+func (s *Redis) SaveMarkPrices(ctx context.Context, prices []models.PriceResponse) error {
+	return s.savePrices(ctx, "SaveMarkPrices", markPrefix, prices)
+}
+
+func (s *Redis) savePrices(ctx context.Context, method, keyPrefix string, prices []models.PriceResponse) error {
+	log := slog.With("method", method)
 	pipe := s.client.Pipeline()
 
 	for _, priceResp := range prices {
-		key := fmt.Sprintf("%s:%s", prefix, priceResp.Symbol)
-		value, _ := json.Marshal(priceResp.Price)
+		key := fmt.Sprintf("%s:%s", keyPrefix, priceResp.Symbol)
+		value, _ := json.Marshal(models.Quote{Price: priceResp.Price, Time: priceResp.Time})
 		pipe.Set(ctx, key, value, 10*time.Minute)
 	}
 	_, err := pipe.Exec(ctx)
@@ -94,10 +114,79 @@ func (s *Redis) SavePrices(ctx context.Context, prices []models.PriceResponse) e
 
 func (s *Redis) GetLiqOrders(ctx context.Context, key, price string) ([]uuid.UUID, error) {
 	const method = "GetLiqOrders"
+
+	return s.getTriggeredOrders(ctx, method, orderPrefix, key, price)
+}
+
+// GetNearLiquidation returns positions close to liquidation but not liquidated yet:
+// longs with liquidation price in [low, price) and shorts with liquidation price in (price, high]
+func (s *Redis) GetNearLiquidation(ctx context.Context, key, price, low, high string) ([]uuid.UUID, error) {
+	const method = "GetNearLiquidation"
+
+	longOrders, err := s.rangeOrders(ctx, orderPrefix+"long:"+key, low, "("+price)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s:%w", method, "long", err)
+	}
+	shortOrders, err := s.rangeOrders(ctx, orderPrefix+"short:"+key, "("+price, high)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s:%w", method, "short", err)
+	}
+
+	return append(longOrders, shortOrders...), nil
+}
+
+// SavePendingOrder indexes limit order by its limit price: orders:pending:long:BTC/USDT
+func (s *Redis) SavePendingOrder(ctx context.Context, order models.Order) error {
+	const method = "SavePendingOrder"
+	log := slog.With("method", method)
+	if order.LimitPrice == nil {
+		return fmt.Errorf("%s: limit price is empty", method)
+	}
+
+	parsedLimitPrice, err := strconv.ParseFloat(order.LimitPrice.String(), 64)
+	if err != nil {
+		log.Error("failed to parse limit price", "err", err)
+		return fmt.Errorf("limit price parse err: %s:%w", "err", err)
+	}
+
+	curPrefix := fmt.Sprintf("%s%s:%s", pendingOrderPrefix, string(order.Type), order.Ticker)
+	err = s.client.ZAdd(ctx, curPrefix, &redis.Z{
+		Score: parsedLimitPrice, Member: order.Id.String(),
+	}).Err()
+	if err != nil {
+		log.Error("failed to save pending order", "err", err, "id", order.Id)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	log.Info("saved pending order to redis-sorted-set", "id", order.Id)
+	return nil
+}
+
+func (s *Redis) RemovePendingOrder(ctx context.Context, id, ticker string, orderType models.OrderType) error {
+	const method = "RemovePendingOrder"
+	curPrefix := fmt.Sprintf("%s%s:%s", pendingOrderPrefix, string(orderType), ticker)
+	err := s.client.ZRem(ctx, curPrefix, id).Err()
+	if err != nil {
+		slog.Error("failed to remove pending order", "method", method, "err", err, "id", id)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
+
+// GetFillableOrders returns pending longs with limit >= price and pending shorts with limit <= price
+func (s *Redis) GetFillableOrders(ctx context.Context, key, price string) ([]uuid.UUID, error) {
+	const method = "GetFillableOrders"
+
+	return s.getTriggeredOrders(ctx, method, pendingOrderPrefix, key, price)
+}
+
+// getTriggeredOrders returns long members with score >= price and short members with score <= price
+func (s *Redis) getTriggeredOrders(ctx context.Context, method, setPrefix, key, price string) ([]uuid.UUID, error) {
 	log := slog.With("method", method)
 
-	const longPrefix = "orders:long:"
-	const shortPrefix = "orders:short:"
+	longPrefix := setPrefix + "long:"
+	shortPrefix := setPrefix + "short:"
 	const minInf = "-inf"
 	const maxInf = "+inf"
 	maxScore := price
@@ -106,7 +195,7 @@ func (s *Redis) GetLiqOrders(ctx context.Context, key, price string) ([]uuid.UUI
 		Min: maxScore, Max: maxInf,
 	}).Result()
 	if err != nil {
-		log.Error("failed to get long orders for liq by Zrange", "err", err)
+		log.Error("failed to get long orders by Zrange", "err", err)
 		return nil, fmt.Errorf("%s:%s:%w", method, "long", err)
 	}
 
@@ -114,6 +203,10 @@ func (s *Redis) GetLiqOrders(ctx context.Context, key, price string) ([]uuid.UUI
 	shortOrders, err := s.client.ZRangeByScore(ctx, shortPrefix+key, &redis.ZRangeBy{
 		Min: minInf, Max: minScore,
 	}).Result()
+	if err != nil {
+		log.Error("failed to get short orders by Zrange", "err", err)
+		return nil, fmt.Errorf("%s:%s:%w", method, "short", err)
+	}
 
 	allOrders := append(longOrders, shortOrders...)
 	result := make([]uuid.UUID, 0, len(allOrders))
@@ -141,7 +234,7 @@ func (s *Redis) GetAllPrices(ctx context.Context) ([]models.PriceResponse, error
 	for _, jsonData := range data {
 		var price models.PriceResponse
 		if err := json.Unmarshal([]byte(jsonData), &price); err == nil {
-			log.Debug("priceResponse", price)
+			log.Debug("priceResponse", "price", price)
 			prices = append(prices, price)
 		}
 	}
@@ -149,30 +242,70 @@ func (s *Redis) GetAllPrices(ctx context.Context) ([]models.PriceResponse, error
 }
 
 func (s *Redis) GetPrice(ctx context.Context, ticker string) (string, error) {
-	log := slog.With("method", "GetPrice")
+	quote, err := s.getQuote(ctx, "GetPrice", prefix, ticker)
+	return quote.Price, err
+}
+
+// GetQuote returns last price with time it was quoted at by exchange
+func (s *Redis) GetQuote(ctx context.Context, ticker string) (models.Quote, error) {
+	return s.getQuote(ctx, "GetQuote", prefix, ticker)
+}
+
+// Prices is view of index or mark prices with the same GetPrice as last prices
+type Prices struct {
+	redis     *Redis
+	method    string
+	keyPrefix string
+}
+
+func (s *Redis) IndexPrices() Prices {
+	return Prices{redis: s, method: "GetIndexPrice", keyPrefix: indexPrefix}
+}
+
+func (s *Redis) MarkPrices() Prices {
+	return Prices{redis: s, method: "GetMarkPrice", keyPrefix: markPrefix}
+}
+
+func (p Prices) GetPrice(ctx context.Context, ticker string) (string, error) {
+	quote, err := p.redis.getQuote(ctx, p.method, p.keyPrefix, ticker)
+	return quote.Price, err
+}
+
+func (p Prices) GetQuote(ctx context.Context, ticker string) (models.Quote, error) {
+	return p.redis.getQuote(ctx, p.method, p.keyPrefix, ticker)
+}
+
+func (s *Redis) getQuote(ctx context.Context, method, keyPrefix, ticker string) (models.Quote, error) {
+	log := slog.With("method", method)
 
 	tickerRedis := ticker
 	if strings.Contains(ticker, "/") {
 		parts := strings.Split(ticker, "/")
 		if len(parts) != 2 {
-			return "", fmt.Errorf("invalid ticker: %s", ticker)
+			return models.Quote{}, fmt.Errorf("invalid ticker: %s", ticker)
 		}
 		tickerRedis = parts[0] + parts[1]
 		log.Debug("ticker modified", "ticker", ticker)
 	}
 
-	data, err := s.client.Get(ctx, prefix+":"+tickerRedis).Result()
+	data, err := s.client.Get(ctx, keyPrefix+":"+tickerRedis).Result()
+	if errors.Is(err, redis.Nil) {
+		log.Warn("no price", "key", keyPrefix+":"+tickerRedis)
+		return models.Quote{}, fmt.Errorf("%w: %s", ErrPriceNotFound, ticker)
+	}
 	if err != nil {
 		log.Error("failed to get price", "err", err)
-		return "", fmt.Errorf("failed to get prices: %w", err)
+		return models.Quote{}, fmt.Errorf("failed to get prices: %w", err)
 	}
-	var price string
-	err = json.Unmarshal([]byte(data), &price)
-	if err != nil {
-		log.Error("failed to unmarshal price", "data", data, "err", err)
-		return "", fmt.Errorf("failed to unmarshal prices: %w", err)
+	var quote models.Quote
+	if err := json.Unmarshal([]byte(data), &quote); err != nil {
+		// price set by hand (test ticker) is bare string without time
+		if err := json.Unmarshal([]byte(data), &quote.Price); err != nil {
+			log.Error("failed to unmarshal price", "data", data, "err", err)
+			return models.Quote{}, fmt.Errorf("failed to unmarshal prices: %w", err)
+		}
 	}
 
-	log.Debug("successfully get price from redis", "price", price)
-	return price, nil
+	log.Debug("successfully get price from redis", "price", quote.Price)
+	return quote, nil
 }
@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const (
+	refreshSessionPrefix = "sessions:refresh:"
+	userSessionsPrefix   = "sessions:user:"
+	revokedTokenPrefix   = "sessions:revoked:"
+	revokedBeforePrefix  = "sessions:revoked_before:"
+)
+
+// SaveRefreshSession stores refresh token id until it expires
+func (s *Redis) SaveRefreshSession(ctx context.Context, userId int64, jti string, ttl time.Duration) error {
+	const method = "SaveRefreshSession"
+	log := slog.With("method", method)
+
+	userKey := userSessionsPrefix + strconv.FormatInt(userId, 10)
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, refreshSessionPrefix+jti, userId, ttl)
+	pipe.SAdd(ctx, userKey, jti)
+	pipe.Expire(ctx, userKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Error("failed to save refresh session", "err", err, "user_id", userId)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
+
+// ConsumeRefreshSession deletes refresh session and reports whether it existed,
+// so one refresh token can be exchanged only once
+func (s *Redis) ConsumeRefreshSession(ctx context.Context, userId int64, jti string) (bool, error) {
+	const method = "ConsumeRefreshSession"
+	log := slog.With("method", method)
+
+	deleted, err := s.client.Del(ctx, refreshSessionPrefix+jti).Result()
+	if err != nil {
+		log.Error("failed to delete refresh session", "err", err, "user_id", userId)
+		return false, fmt.Errorf("%s: %w", method, err)
+	}
+	s.client.SRem(ctx, userSessionsPrefix+strconv.FormatInt(userId, 10), jti)
+
+	return deleted > 0, nil
+}
+
+// RevokeToken blacklists access token id for the rest of its lifetime
+func (s *Redis) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	const method = "RevokeToken"
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := s.client.Set(ctx, revokedTokenPrefix+jti, 1, ttl).Err(); err != nil {
+		slog.Error("failed to revoke token", "method", method, "err", err)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions drops every refresh session of user and invalidates
+// access tokens issued before now, up to millisecond
+func (s *Redis) RevokeUserSessions(ctx context.Context, userId int64, ttl time.Duration) error {
+	const method = "RevokeUserSessions"
+	log := slog.With("method", method)
+
+	userKey := userSessionsPrefix + strconv.FormatInt(userId, 10)
+	jtis, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		log.Error("failed to get user sessions", "err", err, "user_id", userId)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	pipe := s.client.TxPipeline()
+	for _, jti := range jtis {
+		pipe.Del(ctx, refreshSessionPrefix+jti)
+	}
+	pipe.Del(ctx, userKey)
+	pipe.Set(ctx, revokedBeforePrefix+strconv.FormatInt(userId, 10), time.Now().UnixMilli(), ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Error("failed to revoke user sessions", "err", err, "user_id", userId)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	log.Info("revoked user sessions", "user_id", userId, "count", len(jtis))
+	return nil
+}
+
+// IsTokenRevoked checks token blacklist and user-wide revocation mark
+func (s *Redis) IsTokenRevoked(ctx context.Context, userId int64, jti string, issuedAt time.Time) (bool, error) {
+	const method = "IsTokenRevoked"
+
+	pipe := s.client.Pipeline()
+	revoked := pipe.Exists(ctx, revokedTokenPrefix+jti)
+	revokedBefore := pipe.Get(ctx, revokedBeforePrefix+strconv.FormatInt(userId, 10))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		slog.Error("failed to check token revocation", "method", method, "err", err)
+		return false, fmt.Errorf("%s: %w", method, err)
+	}
+
+	if revoked.Val() > 0 {
+		return true, nil
+	}
+
+	before, err := revokedBefore.Int64()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		// corrupt mark can't tell which tokens it revokes
+		slog.Error("invalid revocation mark", "method", method, "err", err, "user_id", userId)
+		return false, fmt.Errorf("%s: %w", method, err)
+	}
+
+	// token issued in the same millisecond as revocation is issued after it
+	return issuedAt.UnixMilli() < before, nil
+}
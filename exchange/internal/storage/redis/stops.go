@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"strconv"
+)
+
+const (
+	stopLossPrefix   = "orders:sl:"
+	takeProfitPrefix = "orders:tp:"
+)
+
+// SaveOrderStops indexes stop-loss and take-profit of open order the same way
+// SaveOrder indexes liquidation price: orders:sl:long:BTC/USDT, orders:tp:short:BTC/USDT.
+// Empty level removes order from its set
+func (s *Redis) SaveOrderStops(ctx context.Context, order models.Order) error {
+	const method = "SaveOrderStops"
+	log := slog.With("method", method)
+
+	pipe := s.client.TxPipeline()
+	for setPrefix, level := range map[string]*decimal.Decimal{
+		stopLossPrefix:   order.StopLoss,
+		takeProfitPrefix: order.TakeProfit,
+	} {
+		key := fmt.Sprintf("%s%s:%s", setPrefix, string(order.Type), order.Ticker)
+		if level == nil {
+			pipe.ZRem(ctx, key, order.Id.String())
+			continue
+		}
+
+		score, err := strconv.ParseFloat(level.String(), 64)
+		if err != nil {
+			log.Error("failed to parse stop level", "err", err)
+			return fmt.Errorf("stop level parse err: %s:%w", "err", err)
+		}
+		pipe.ZAdd(ctx, key, &redis.Z{Score: score, Member: order.Id.String()})
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Error("failed to save order stops", "err", err, "id", order.Id)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	log.Info("saved order stops to redis-sorted-set", "id", order.Id)
+	return nil
+}
+
+func (s *Redis) RemoveOrderStops(ctx context.Context, id, ticker string, orderType models.OrderType) error {
+	const method = "RemoveOrderStops"
+
+	pipe := s.client.TxPipeline()
+	pipe.ZRem(ctx, fmt.Sprintf("%s%s:%s", stopLossPrefix, string(orderType), ticker), id)
+	pipe.ZRem(ctx, fmt.Sprintf("%s%s:%s", takeProfitPrefix, string(orderType), ticker), id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("failed to remove order stops", "method", method, "err", err, "id", id)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
+
+// GetStopOrders returns orders whose stop-loss or take-profit is crossed by price.
+// Stop-loss triggers like liquidation (long score >= price, short score <= price),
+// take-profit triggers the other way round
+func (s *Redis) GetStopOrders(ctx context.Context, key, price string) ([]uuid.UUID, error) {
+	const method = "GetStopOrders"
+
+	stopLoss, err := s.getTriggeredOrders(ctx, method, stopLossPrefix, key, price)
+	if err != nil {
+		return nil, err
+	}
+
+	const minInf = "-inf"
+	const maxInf = "+inf"
+	longTakeProfit, err := s.rangeOrders(ctx, takeProfitPrefix+"long:"+key, minInf, price)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s:%w", method, "long", err)
+	}
+	shortTakeProfit, err := s.rangeOrders(ctx, takeProfitPrefix+"short:"+key, price, maxInf)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s:%w", method, "short", err)
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(stopLoss))
+	result := make([]uuid.UUID, 0, len(stopLoss)+len(longTakeProfit)+len(shortTakeProfit))
+	for _, ids := range [][]uuid.UUID{stopLoss, longTakeProfit, shortTakeProfit} {
+		for _, id := range ids {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			result = append(result, id)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Redis) rangeOrders(ctx context.Context, key, minScore, maxScore string) ([]uuid.UUID, error) {
+	members, err := s.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: minScore, Max: maxScore,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]uuid.UUID, 0, len(members))
+	for _, idStr := range members {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			slog.Error("failed to parse UUID", "id", idStr, "err", err)
+			continue
+		}
+		result = append(result, id)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"Exchange/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"log/slog"
+	"strconv"
+)
+
+const (
+	// trailing stop level, triggers like liquidation price: orders:trail:long:BTC/USDT
+	trailingLevelPrefix = "orders:trail:"
+	// best price reached by position: orders:trail_extreme:long:BTC/USDT
+	trailingExtremePrefix = "orders:trail_extreme:"
+)
+
+// SaveTrailingStop indexes trailing stop level and extreme of open order
+func (s *Redis) SaveTrailingStop(ctx context.Context, order models.Order) error {
+	const method = "SaveTrailingStop"
+	log := slog.With("method", method)
+	if order.TrailingStop == nil {
+		return s.RemoveTrailingStop(ctx, order.Id.String(), order.Ticker, order.Type)
+	}
+
+	level, err := strconv.ParseFloat(order.TrailingStop.Level(order.Type).String(), 64)
+	if err != nil {
+		log.Error("failed to parse trailing level", "err", err)
+		return fmt.Errorf("trailing level parse err: %s:%w", "err", err)
+	}
+	extreme, err := strconv.ParseFloat(order.TrailingStop.Extreme.String(), 64)
+	if err != nil {
+		log.Error("failed to parse trailing extreme", "err", err)
+		return fmt.Errorf("trailing extreme parse err: %s:%w", "err", err)
+	}
+
+	suffix := fmt.Sprintf("%s:%s", string(order.Type), order.Ticker)
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, trailingLevelPrefix+suffix, &redis.Z{Score: level, Member: order.Id.String()})
+	pipe.ZAdd(ctx, trailingExtremePrefix+suffix, &redis.Z{Score: extreme, Member: order.Id.String()})
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Error("failed to save trailing stop", "err", err, "id", order.Id)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
+
+func (s *Redis) RemoveTrailingStop(ctx context.Context, id, ticker string, orderType models.OrderType) error {
+	const method = "RemoveTrailingStop"
+
+	suffix := fmt.Sprintf("%s:%s", string(orderType), ticker)
+	pipe := s.client.TxPipeline()
+	pipe.ZRem(ctx, trailingLevelPrefix+suffix, id)
+	pipe.ZRem(ctx, trailingExtremePrefix+suffix, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("failed to remove trailing stop", "method", method, "err", err, "id", id)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
+
+// GetTrailingToMove returns orders whose extreme is beaten by price:
+// longs with extreme < price and shorts with extreme > price
+func (s *Redis) GetTrailingToMove(ctx context.Context, key, price string) ([]uuid.UUID, error) {
+	const method = "GetTrailingToMove"
+
+	longOrders, err := s.rangeOrders(ctx, trailingExtremePrefix+"long:"+key, "-inf", "("+price)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s:%w", method, "long", err)
+	}
+	shortOrders, err := s.rangeOrders(ctx, trailingExtremePrefix+"short:"+key, "("+price, "+inf")
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s:%w", method, "short", err)
+	}
+
+	return append(longOrders, shortOrders...), nil
+}
+
+// GetTrailingTriggered returns orders whose trailing level is crossed by price
+func (s *Redis) GetTrailingTriggered(ctx context.Context, key, price string) ([]uuid.UUID, error) {
+	const method = "GetTrailingTriggered"
+
+	return s.getTriggeredOrders(ctx, method, trailingLevelPrefix, key, price)
+}
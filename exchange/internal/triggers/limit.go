@@ -0,0 +1,58 @@
+package triggers
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"log/slog"
+)
+
+type fillableFinder interface {
+	GetFillableOrders(ctx context.Context, key, price string) ([]uuid.UUID, error)
+}
+
+type limitFiller interface {
+	FillLimitOrder(ctx context.Context, orderId uuid.UUID, price decimal.Decimal) (uuid.UUID, error)
+}
+
+// LimitEngine fills pending limit orders once price reaches their limit
+type LimitEngine struct {
+	log    *slog.Logger
+	finder fillableFinder
+	filler limitFiller
+}
+
+func NewLimitEngine(log *slog.Logger, finder fillableFinder, filler limitFiller) *LimitEngine {
+	return &LimitEngine{
+		log:    log,
+		finder: finder,
+		filler: filler,
+	}
+}
+
+// HandlePrice fills every order triggered by price of ticker (BTC/USDT)
+func (e *LimitEngine) HandlePrice(ctx context.Context, ticker string, price string) {
+	const op = "triggers.LimitEngine.HandlePrice"
+	log := e.log.With("op", op, "ticker", ticker)
+
+	priceDec, err := decimal.NewFromString(price)
+	if err != nil {
+		log.Error("invalid price", "price", price, "error", err)
+		return
+	}
+
+	orders, err := e.finder.GetFillableOrders(ctx, ticker, price)
+	if err != nil {
+		log.Error("get fillable orders failed", "error", err)
+		return
+	}
+
+	for _, orderId := range orders {
+		id, err := e.filler.FillLimitOrder(ctx, orderId, priceDec)
+		if err != nil {
+			log.Error("limit order fill failed", "order_id", orderId, "error", err)
+			continue
+		}
+		log.Info("limit order filled", "order_id", id, "price", price)
+	}
+}
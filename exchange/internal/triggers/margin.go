@@ -0,0 +1,120 @@
+package triggers
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/events"
+	"Exchange/internal/domain/models"
+	"context"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+type nearLiquidationFinder interface {
+	GetNearLiquidation(ctx context.Context, key, price, low, high string) ([]uuid.UUID, error)
+}
+
+type orderGetter interface {
+	GetOrder(ctx context.Context, orderID uuid.UUID) (models.Order, error)
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, subject string, msg any) error
+}
+
+// MarginEngine warns owners of positions whose liquidation price is close to price
+type MarginEngine struct {
+	log       *slog.Logger
+	finder    nearLiquidationFinder
+	orders    orderGetter
+	publisher eventPublisher
+	threshold decimal.Decimal
+	cooldown  time.Duration
+
+	mu     sync.Mutex
+	warned map[uuid.UUID]time.Time
+}
+
+func NewMarginEngine(log *slog.Logger,
+	cfg config.MarginConfig,
+	finder nearLiquidationFinder,
+	orders orderGetter,
+	publisher eventPublisher) *MarginEngine {
+	return &MarginEngine{
+		log:       log,
+		finder:    finder,
+		orders:    orders,
+		publisher: publisher,
+		threshold: decimal.NewFromFloat(cfg.Threshold),
+		cooldown:  cfg.Cooldown,
+		warned:    make(map[uuid.UUID]time.Time),
+	}
+}
+
+// HandlePrice publishes margin.warning for positions of ticker (BTC/USDT) near liquidation,
+// each position is warned at most once per cooldown
+func (e *MarginEngine) HandlePrice(ctx context.Context, ticker string, price string) {
+	const op = "triggers.MarginEngine.HandlePrice"
+	log := e.log.With("op", op, "ticker", ticker)
+
+	priceDec, err := decimal.NewFromString(price)
+	if err != nil {
+		log.Error("invalid price", "price", price, "error", err)
+		return
+	}
+
+	one := decimal.NewFromInt(1)
+	low := priceDec.Mul(one.Sub(e.threshold)).String()
+	high := priceDec.Mul(one.Add(e.threshold)).String()
+	orders, err := e.finder.GetNearLiquidation(ctx, ticker, price, low, high)
+	if err != nil {
+		log.Error("get positions near liquidation failed", "error", err)
+		return
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id, at := range e.warned {
+		if now.Sub(at) >= e.cooldown {
+			delete(e.warned, id)
+		}
+	}
+
+	for _, orderId := range orders {
+		if _, ok := e.warned[orderId]; ok {
+			continue
+		}
+
+		order, err := e.orders.GetOrder(ctx, orderId)
+		if err != nil {
+			log.Error("get order failed", "order_id", orderId, "error", err)
+			continue
+		}
+		if order.Status != models.Open {
+			continue
+		}
+
+		event, err := events.New(events.MarginWarning, order.UserId, events.Margin{
+			OrderId:          order.Id,
+			Ticker:           strings.TrimSpace(order.Ticker),
+			Type:             order.Type,
+			Price:            priceDec,
+			LiquidationPrice: order.LiquidationPrice,
+		})
+		if err != nil {
+			log.Error("build margin warning failed", "order_id", orderId, "error", err)
+			continue
+		}
+		if err := e.publisher.Publish(ctx, event.Subject(), event); err != nil {
+			log.Error("publish margin warning failed", "order_id", orderId, "error", err)
+			continue
+		}
+
+		e.warned[orderId] = now
+		log.Info("margin warning sent", "order_id", orderId, "price", price)
+	}
+}
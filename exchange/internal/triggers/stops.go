@@ -0,0 +1,58 @@
+package triggers
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"log/slog"
+)
+
+type stopFinder interface {
+	GetStopOrders(ctx context.Context, key, price string) ([]uuid.UUID, error)
+}
+
+type stopCloser interface {
+	CloseTriggeredDeal(ctx context.Context, orderId uuid.UUID, price decimal.Decimal) (uuid.UUID, error)
+}
+
+// StopEngine closes positions whose stop-loss or take-profit is crossed
+type StopEngine struct {
+	log    *slog.Logger
+	finder stopFinder
+	closer stopCloser
+}
+
+func NewStopEngine(log *slog.Logger, finder stopFinder, closer stopCloser) *StopEngine {
+	return &StopEngine{
+		log:    log,
+		finder: finder,
+		closer: closer,
+	}
+}
+
+// HandlePrice closes every position triggered by price of ticker (BTC/USDT)
+func (e *StopEngine) HandlePrice(ctx context.Context, ticker string, price string) {
+	const op = "triggers.StopEngine.HandlePrice"
+	log := e.log.With("op", op, "ticker", ticker)
+
+	priceDec, err := decimal.NewFromString(price)
+	if err != nil {
+		log.Error("invalid price", "price", price, "error", err)
+		return
+	}
+
+	orders, err := e.finder.GetStopOrders(ctx, ticker, price)
+	if err != nil {
+		log.Error("get stop orders failed", "error", err)
+		return
+	}
+
+	for _, orderId := range orders {
+		id, err := e.closer.CloseTriggeredDeal(ctx, orderId, priceDec)
+		if err != nil {
+			log.Error("stop close failed", "order_id", orderId, "error", err)
+			continue
+		}
+		log.Info("position closed by stop", "order_id", id, "price", price)
+	}
+}
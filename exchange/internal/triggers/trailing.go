@@ -0,0 +1,82 @@
+package triggers
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"log/slog"
+)
+
+type trailingFinder interface {
+	GetTrailingToMove(ctx context.Context, key, price string) ([]uuid.UUID, error)
+	GetTrailingTriggered(ctx context.Context, key, price string) ([]uuid.UUID, error)
+}
+
+type trailingMover interface {
+	MoveTrailingStop(ctx context.Context, orderId uuid.UUID, price decimal.Decimal) error
+	CloseTriggeredDeal(ctx context.Context, orderId uuid.UUID, price decimal.Decimal) (uuid.UUID, error)
+	RestoreTrailingStops(ctx context.Context) (int, error)
+}
+
+// TrailingEngine moves trailing stops after price and closes positions on pullback
+type TrailingEngine struct {
+	log    *slog.Logger
+	finder trailingFinder
+	mover  trailingMover
+}
+
+func NewTrailingEngine(log *slog.Logger, finder trailingFinder, mover trailingMover) *TrailingEngine {
+	return &TrailingEngine{
+		log:    log,
+		finder: finder,
+		mover:  mover,
+	}
+}
+
+// Restore rebuilds trailing stop index from persisted state, call it before consuming prices
+func (e *TrailingEngine) Restore(ctx context.Context) error {
+	count, err := e.mover.RestoreTrailingStops(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.log.Info("trailing stops restored", "count", count)
+	return nil
+}
+
+// HandlePrice moves trailing stops of ticker (BTC/USDT) and closes triggered positions
+func (e *TrailingEngine) HandlePrice(ctx context.Context, ticker string, price string) {
+	const op = "triggers.TrailingEngine.HandlePrice"
+	log := e.log.With("op", op, "ticker", ticker)
+
+	priceDec, err := decimal.NewFromString(price)
+	if err != nil {
+		log.Error("invalid price", "price", price, "error", err)
+		return
+	}
+
+	toMove, err := e.finder.GetTrailingToMove(ctx, ticker, price)
+	if err != nil {
+		log.Error("get trailing stops to move failed", "error", err)
+		return
+	}
+	for _, orderId := range toMove {
+		if err := e.mover.MoveTrailingStop(ctx, orderId, priceDec); err != nil {
+			log.Error("trailing stop move failed", "order_id", orderId, "error", err)
+		}
+	}
+
+	triggered, err := e.finder.GetTrailingTriggered(ctx, ticker, price)
+	if err != nil {
+		log.Error("get triggered trailing stops failed", "error", err)
+		return
+	}
+	for _, orderId := range triggered {
+		id, err := e.mover.CloseTriggeredDeal(ctx, orderId, priceDec)
+		if err != nil {
+			log.Error("trailing stop close failed", "order_id", orderId, "error", err)
+			continue
+		}
+		log.Info("position closed by trailing stop", "order_id", id, "price", price)
+	}
+}
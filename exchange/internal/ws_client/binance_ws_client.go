@@ -0,0 +1,259 @@
+package ws_client
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const writeWait = 10 * time.Second
+
+// BinanceWSClient streams prices of configured symbols from Binance combined
+// @trade and @bookTicker streams. Last price comes from trades only, so quiet book
+// can't make dead trade feed look fresh. Best bid and ask are kept apart from it
+type BinanceWSClient struct {
+	url     string
+	streams []string
+	cfg     config.BinanceConfig
+	log     slog.Logger
+	dialer  *websocket.Dialer
+
+	mu     sync.Mutex
+	latest map[string]models.PriceResponse
+	books  map[string]BookTicker
+}
+
+// BookTicker is best bid and ask of symbol, Time is when it was received
+type BookTicker struct {
+	Symbol string
+	Bid    decimal.Decimal
+	Ask    decimal.Decimal
+	Time   time.Time
+}
+
+// Mid is price between best bid and ask
+func (b BookTicker) Mid() decimal.Decimal {
+	return b.Bid.Add(b.Ask).Div(decimal.NewFromInt(2))
+}
+
+// combinedMessage is envelope of combined stream: {"stream":"btcusdt@trade","data":{...}}
+type combinedMessage struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type tradeData struct {
+	Symbol string `json:"s"`
+	Price  string `json:"p"`
+	// TradeTime is unix milliseconds
+	TradeTime int64 `json:"T"`
+}
+
+type bookTickerData struct {
+	Symbol string `json:"s"`
+	Bid    string `json:"b"`
+	Ask    string `json:"a"`
+}
+
+func New(cfg config.Config, log slog.Logger) *BinanceWSClient {
+	return &BinanceWSClient{
+		url:     cfg.BinanceConfig.WSURL,
+		streams: cfg.BinanceConfig.Streams,
+		cfg:     cfg.BinanceConfig,
+		log:     log,
+		dialer:  &websocket.Dialer{HandshakeTimeout: 10 * time.Second},
+		latest:  make(map[string]models.PriceResponse),
+		books:   make(map[string]BookTicker),
+	}
+}
+
+// Run streams prices until ctx is done, reconnecting with exponential backoff.
+// Updates are coalesced: every flush interval onPrices gets latest price of each
+// symbol changed since previous flush
+func (c *BinanceWSClient) Run(ctx context.Context, onPrices func([]models.PriceResponse)) {
+	log := c.log.With("method", "Run")
+
+	go c.flushLoop(ctx, onPrices)
+
+	backoff := c.cfg.ReconnectMin
+	for {
+		started := time.Now()
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		// session which lived longer than max backoff was healthy, start over
+		if time.Since(started) > c.cfg.ReconnectMax {
+			backoff = c.cfg.ReconnectMin
+		}
+		log.Warn("binance stream disconnected, reconnecting", "error", err, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, c.cfg.ReconnectMax)
+	}
+}
+
+// session reads one connection until it fails or ctx is done
+func (c *BinanceWSClient) session(ctx context.Context) error {
+	conn, _, err := c.dialer.DialContext(ctx, c.streamURL(), nil)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	c.log.Info("connected to binance stream", "streams", len(c.streams))
+
+	// connection without any frame for two ping intervals is dead
+	readWait := 2 * c.cfg.PingInterval
+	conn.SetReadDeadline(time.Now().Add(readWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readWait))
+	})
+	// binance pings every few minutes and drops connection which doesn't answer
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(readWait))
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeWait))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.pingLoop(conn, done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(readWait))
+		c.handleMessage(data)
+	}
+}
+
+func (c *BinanceWSClient) pingLoop(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(c.cfg.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *BinanceWSClient) handleMessage(data []byte) {
+	var msg combinedMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.log.Error("invalid binance message", "error", err)
+		return
+	}
+
+	switch {
+	case strings.HasSuffix(msg.Stream, "@trade"):
+		var trade tradeData
+		if err := json.Unmarshal(msg.Data, &trade); err != nil {
+			c.log.Error("invalid binance trade", "stream", msg.Stream, "error", err)
+			return
+		}
+		price := models.PriceResponse{Symbol: trade.Symbol, Price: trade.Price, Time: time.UnixMilli(trade.TradeTime)}
+
+		c.mu.Lock()
+		// trades of one symbol may arrive out of order between flushes
+		if prev, ok := c.latest[price.Symbol]; !ok || !price.Time.Before(prev.Time) {
+			c.latest[price.Symbol] = price
+		}
+		c.mu.Unlock()
+	case strings.HasSuffix(msg.Stream, "@bookTicker"):
+		var data bookTickerData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			c.log.Error("invalid binance book ticker", "stream", msg.Stream, "error", err)
+			return
+		}
+		bid, err := decimal.NewFromString(data.Bid)
+		if err != nil {
+			return
+		}
+		ask, err := decimal.NewFromString(data.Ask)
+		if err != nil || bid.IsZero() || ask.IsZero() {
+			return
+		}
+
+		c.mu.Lock()
+		// book ticker carries no time, it is quoted right now
+		c.books[data.Symbol] = BookTicker{Symbol: data.Symbol, Bid: bid, Ask: ask, Time: time.Now()}
+		c.mu.Unlock()
+	}
+}
+
+// Book returns latest best bid and ask of symbol (BTCUSDT)
+func (c *BinanceWSClient) Book(symbol string) (BookTicker, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	book, ok := c.books[symbol]
+	return book, ok
+}
+
+func (c *BinanceWSClient) flushLoop(ctx context.Context, onPrices func([]models.PriceResponse)) {
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if prices := c.flush(); len(prices) > 0 {
+				onPrices(prices)
+			}
+		}
+	}
+}
+
+// flush takes last prices traded since previous flush
+func (c *BinanceWSClient) flush() []models.PriceResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prices := make([]models.PriceResponse, 0, len(c.latest))
+	for _, price := range c.latest {
+		prices = append(prices, price)
+	}
+	clear(c.latest)
+	return prices
+}
+
+// streamURL builds combined stream url: /stream?streams=btcusdt@trade/btcusdt@bookTicker
+func (c *BinanceWSClient) streamURL() string {
+	names := make([]string, 0, 2*len(c.streams))
+	for _, stream := range c.streams {
+		symbol := strings.ToLower(stream)
+		names = append(names, symbol+"@trade", symbol+"@bookTicker")
+	}
+	return fmt.Sprintf("%s/stream?streams=%s", c.url, strings.Join(names, "/"))
+}
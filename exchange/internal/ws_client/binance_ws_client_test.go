@@ -0,0 +1,270 @@
+package ws_client
+
+import (
+	"Exchange/internal/config"
+	"Exchange/internal/domain/models"
+	"context"
+	"github.com/gorilla/websocket"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stub is local Binance stream server, serve handles every accepted connection
+type stub struct {
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+	conns    atomic.Int32
+
+	mu       sync.Mutex
+	accepted []time.Time
+	query    string
+}
+
+func newStub(t *testing.T, serve func(conn *websocket.Conn, n int32)) *stub {
+	t.Helper()
+	s := &stub{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		s.mu.Lock()
+		s.accepted = append(s.accepted, time.Now())
+		s.query = r.URL.RawQuery
+		s.mu.Unlock()
+		serve(conn, s.conns.Add(1))
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stub) acceptedAt() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time(nil), s.accepted...)
+}
+
+func (s *stub) wsURL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+func newTestClient(url string) *BinanceWSClient {
+	cfg := config.Config{BinanceConfig: config.BinanceConfig{
+		WSURL:         url,
+		Streams:       []string{"BTCUSDT"},
+		PingInterval:  50 * time.Millisecond,
+		ReconnectMin:  20 * time.Millisecond,
+		ReconnectMax:  time.Second,
+		FlushInterval: 10 * time.Millisecond,
+	}}
+	return New(cfg, *slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// run starts client and returns channel of flushed prices, client is stopped on cleanup
+func run(t *testing.T, c *BinanceWSClient) <-chan []models.PriceResponse {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	prices := make(chan []models.PriceResponse, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx, func(p []models.PriceResponse) { prices <- p })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return prices
+}
+
+// readUntilClosed reads connection, so control frames are handled, until it is closed
+func readUntilClosed(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		frame     string
+		wantPrice string
+		wantTime  time.Time
+	}{
+		{
+			name:      "trade",
+			frame:     `{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","p":"62000.01","T":1733486400000}}`,
+			wantPrice: "62000.01",
+			wantTime:  time.UnixMilli(1733486400000),
+		},
+		{name: "book ticker is not last price", frame: `{"stream":"btcusdt@bookTicker","data":{"s":"BTCUSDT","b":"62000","a":"62001"}}`},
+		{name: "unknown stream", frame: `{"stream":"btcusdt@depth","data":{"s":"BTCUSDT"}}`},
+		{name: "malformed envelope", frame: `{"stream":`},
+		{name: "malformed trade", frame: `{"stream":"btcusdt@trade","data":{"p":1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient("")
+			c.handleMessage([]byte(tt.frame))
+			prices := c.flush()
+
+			if tt.wantPrice == "" {
+				if len(prices) != 0 {
+					t.Fatalf("got prices %v, want none", prices)
+				}
+				return
+			}
+			if len(prices) != 1 {
+				t.Fatalf("got %d prices, want 1", len(prices))
+			}
+			got := prices[0]
+			if got.Symbol != "BTCUSDT" || got.Price != tt.wantPrice {
+				t.Errorf("got %s %s, want BTCUSDT %s", got.Symbol, got.Price, tt.wantPrice)
+			}
+			if !tt.wantTime.IsZero() && !got.Time.Equal(tt.wantTime) {
+				t.Errorf("got time %v, want %v", got.Time, tt.wantTime)
+			}
+			if got.Time.IsZero() {
+				t.Error("price has no time")
+			}
+		})
+	}
+}
+
+func TestBookTicker(t *testing.T) {
+	c := newTestClient("")
+	c.handleMessage([]byte(`{"stream":"btcusdt@bookTicker","data":{"s":"BTCUSDT","b":"62000","a":"62001"}}`))
+	c.handleMessage([]byte(`{"stream":"ethusdt@bookTicker","data":{"s":"ETHUSDT","b":"0","a":"3001"}}`))
+
+	book, ok := c.Book("BTCUSDT")
+	if !ok {
+		t.Fatal("no book of BTCUSDT")
+	}
+	if book.Mid().String() != "62000.5" || book.Time.IsZero() {
+		t.Errorf("got mid %s at %v, want 62000.5 now", book.Mid(), book.Time)
+	}
+	if _, ok := c.Book("ETHUSDT"); ok {
+		t.Error("got book without bid, want none")
+	}
+}
+
+func TestFlushKeepsNewestTrade(t *testing.T) {
+	c := newTestClient("")
+	c.handleMessage([]byte(`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","p":"2","T":2}}`))
+	c.handleMessage([]byte(`{"stream":"btcusdt@bookTicker","data":{"s":"BTCUSDT","b":"5","a":"7"}}`))
+	c.handleMessage([]byte(`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","p":"1","T":1}}`))
+
+	prices := c.flush()
+	if len(prices) != 1 || prices[0].Price != "2" {
+		t.Fatalf("got %v, want newest trade 2 only", prices)
+	}
+}
+
+func TestFlushCoalescesUpdates(t *testing.T) {
+	c := newTestClient("")
+	c.handleMessage([]byte(`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","p":"1","T":1}}`))
+	c.handleMessage([]byte(`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","p":"2","T":2}}`))
+
+	prices := c.flush()
+	if len(prices) != 1 || prices[0].Price != "2" {
+		t.Fatalf("got %v, want latest price 2 only", prices)
+	}
+	if prices := c.flush(); len(prices) != 0 {
+		t.Fatalf("second flush got %v, want none", prices)
+	}
+}
+
+func TestRunStreamsFramesFromStub(t *testing.T) {
+	s := newStub(t, func(conn *websocket.Conn, _ int32) {
+		conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","p":"62000.01","T":1733486400000}}`))
+		readUntilClosed(conn)
+	})
+
+	prices := run(t, newTestClient(s.wsURL()))
+	select {
+	case got := <-prices:
+		if len(got) != 1 || got[0].Symbol != "BTCUSDT" || got[0].Price != "62000.01" {
+			t.Fatalf("got %v, want BTCUSDT 62000.01", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no prices received")
+	}
+
+	s.mu.Lock()
+	query := s.query
+	s.mu.Unlock()
+	if query != "streams=btcusdt@trade/btcusdt@bookTicker" {
+		t.Errorf("got query %q", query)
+	}
+}
+
+func TestRunReconnectsWithBackoff(t *testing.T) {
+	// every connection sends one price and is dropped by server
+	s := newStub(t, func(conn *websocket.Conn, n int32) {
+		conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","p":"`+string('0'+n)+`","T":1}}`))
+		time.Sleep(5 * time.Millisecond)
+	})
+
+	run(t, newTestClient(s.wsURL()))
+	deadline := time.Now().Add(3 * time.Second)
+	for s.conns.Load() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	accepted := s.acceptedAt()
+	if len(accepted) < 4 {
+		t.Fatalf("got %d connections, want at least 4", len(accepted))
+	}
+	// backoff starts at ReconnectMin (20ms) and doubles after every drop
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond} {
+		if gap := accepted[i+1].Sub(accepted[i]); gap < want {
+			t.Errorf("reconnect %d after %v, want at least %v", i+1, gap, want)
+		}
+	}
+}
+
+func TestRunKeepsQuietConnectionAliveWithPings(t *testing.T) {
+	var pings, pongs atomic.Int32
+	s := newStub(t, func(conn *websocket.Conn, _ int32) {
+		conn.SetPingHandler(func(data string) error {
+			pings.Add(1)
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		conn.SetPongHandler(func(string) error {
+			pongs.Add(1)
+			return nil
+		})
+		// server pings too, as Binance does
+		conn.WriteControl(websocket.PingMessage, []byte("keepalive"), time.Now().Add(time.Second))
+		readUntilClosed(conn)
+	})
+
+	run(t, newTestClient(s.wsURL()))
+	// no data frames for several read deadlines (2 * PingInterval)
+	time.Sleep(400 * time.Millisecond)
+
+	if n := s.conns.Load(); n != 1 {
+		t.Errorf("got %d connections, want quiet connection to stay open", n)
+	}
+	if n := pings.Load(); n < 3 {
+		t.Errorf("server got %d pings, want at least 3", n)
+	}
+	if n := pongs.Load(); n != 1 {
+		t.Errorf("server got %d pongs, want client to answer server ping", n)
+	}
+}
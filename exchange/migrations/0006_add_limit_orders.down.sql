@@ -0,0 +1,9 @@
+UPDATE users u
+SET balance = u.balance + p.margin
+FROM (SELECT user_id, SUM(margin) AS margin FROM orders WHERE status = 'pending' GROUP BY user_id) p
+WHERE u.id = p.user_id;
+
+UPDATE orders SET status = 'canceled' WHERE status = 'pending';
+
+ALTER TABLE orders
+    DROP COLUMN limit_price;
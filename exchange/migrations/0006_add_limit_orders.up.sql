@@ -0,0 +1,4 @@
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'pending';
+
+ALTER TABLE orders
+    ADD COLUMN limit_price DECIMAL(20, 2);
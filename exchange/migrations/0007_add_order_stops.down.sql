@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    DROP COLUMN stop_loss,
+    DROP COLUMN take_profit;
@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    ADD COLUMN stop_loss   DECIMAL(20, 2),
+    ADD COLUMN take_profit DECIMAL(20, 2);
@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN trailing_type,
+    DROP COLUMN trailing_value,
+    DROP COLUMN trailing_extreme;
+
+DROP TYPE IF EXISTS trailing_type;
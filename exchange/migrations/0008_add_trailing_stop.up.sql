@@ -0,0 +1,6 @@
+CREATE TYPE trailing_type AS ENUM ('percent', 'absolute');
+
+ALTER TABLE orders
+    ADD COLUMN trailing_type    trailing_type,
+    ADD COLUMN trailing_value   DECIMAL(20, 4),
+    ADD COLUMN trailing_extreme DECIMAL(20, 2);
@@ -0,0 +1,11 @@
+CREATE TABLE order_closes
+(
+    id           BIGSERIAL PRIMARY KEY,
+    order_id     UUID           NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    margin       DECIMAL(20, 2) NOT NULL,
+    close_price  DECIMAL(20, 2) NOT NULL,
+    realized_pnl DECIMAL(20, 2) NOT NULL,
+    created_at   TIMESTAMPTZ    NOT NULL
+);
+
+CREATE INDEX idx_order_closes_order_id ON order_closes (order_id);
@@ -0,0 +1,2 @@
+ALTER TABLE orders
+    ADD COLUMN extra_margin DECIMAL(20, 2) NOT NULL DEFAULT 0;
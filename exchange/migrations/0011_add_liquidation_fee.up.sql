@@ -0,0 +1,2 @@
+ALTER TABLE orders
+    ADD COLUMN liquidation_fee DECIMAL(20, 2) NOT NULL DEFAULT 0;
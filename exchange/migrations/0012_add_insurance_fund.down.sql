@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS insurance_fund_history;
+DROP TABLE IF EXISTS insurance_fund;
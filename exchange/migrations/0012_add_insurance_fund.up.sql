@@ -0,0 +1,21 @@
+CREATE TABLE insurance_fund
+(
+    id         SMALLINT PRIMARY KEY,
+    balance    DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ    NOT NULL
+);
+
+INSERT INTO insurance_fund (id, balance, updated_at)
+VALUES (1, 0, NOW());
+
+CREATE TABLE insurance_fund_history
+(
+    id         BIGSERIAL PRIMARY KEY,
+    order_id   UUID REFERENCES orders (id) ON DELETE SET NULL,
+    reason     VARCHAR(32)    NOT NULL,
+    amount     DECIMAL(20, 2) NOT NULL,
+    balance    DECIMAL(20, 2) NOT NULL,
+    created_at TIMESTAMPTZ    NOT NULL
+);
+
+CREATE INDEX idx_insurance_fund_history_created_at ON insurance_fund_history (created_at);
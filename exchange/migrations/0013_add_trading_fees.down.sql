@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS order_fees;
+
+ALTER TABLE order_closes
+    DROP COLUMN fee;
+
+ALTER TABLE orders
+    DROP COLUMN open_fee,
+    DROP COLUMN close_fee;
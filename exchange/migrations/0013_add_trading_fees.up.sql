@@ -0,0 +1,22 @@
+ALTER TABLE orders
+    ADD COLUMN open_fee  DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN close_fee DECIMAL(20, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE order_closes
+    ADD COLUMN fee DECIMAL(20, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE order_fees
+(
+    id         BIGSERIAL PRIMARY KEY,
+    order_id   UUID           NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    user_id    BIGINT         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       VARCHAR(8)     NOT NULL,
+    liquidity  VARCHAR(8)     NOT NULL,
+    notional   DECIMAL(20, 2) NOT NULL,
+    rate       DECIMAL(10, 6) NOT NULL,
+    amount     DECIMAL(20, 2) NOT NULL,
+    created_at TIMESTAMPTZ    NOT NULL
+);
+
+CREATE INDEX idx_order_fees_order_id ON order_fees (order_id);
+CREATE INDEX idx_order_fees_user_id_created_at ON order_fees (user_id, created_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS funding_payments;
+
+ALTER TABLE orders
+    DROP COLUMN funding;
@@ -0,0 +1,18 @@
+ALTER TABLE orders
+    ADD COLUMN funding DECIMAL(20, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE funding_payments
+(
+    id           BIGSERIAL PRIMARY KEY,
+    order_id     UUID           NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    user_id      BIGINT         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ticker       VARCHAR(20)    NOT NULL,
+    rate         DECIMAL(12, 8) NOT NULL,
+    mark_price   DECIMAL(20, 2) NOT NULL,
+    notional     DECIMAL(20, 2) NOT NULL,
+    amount       DECIMAL(20, 2) NOT NULL,
+    funding_time TIMESTAMPTZ    NOT NULL,
+    CONSTRAINT unique_order_funding UNIQUE (order_id, funding_time)
+);
+
+CREATE INDEX idx_funding_payments_user_id ON funding_payments (user_id, funding_time);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ledger_drifts;
+DROP TABLE IF EXISTS ledger_entries;
+DROP SEQUENCE IF EXISTS ledger_journal_seq;
@@ -0,0 +1,65 @@
+CREATE SEQUENCE ledger_journal_seq;
+
+-- each journal (entries with the same journal_id) sums to zero
+CREATE TABLE ledger_entries
+(
+    id         BIGSERIAL PRIMARY KEY,
+    journal_id BIGINT         NOT NULL,
+    kind       VARCHAR(32)    NOT NULL,
+    account    VARCHAR(32)    NOT NULL,
+    user_id    BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    order_id   UUID REFERENCES orders (id) ON DELETE SET NULL,
+    amount     DECIMAL(20, 2) NOT NULL,
+    created_at TIMESTAMPTZ    NOT NULL
+);
+
+CREATE INDEX idx_ledger_entries_journal_id ON ledger_entries (journal_id);
+CREATE INDEX idx_ledger_entries_account_user_id ON ledger_entries (account, user_id);
+CREATE INDEX idx_ledger_entries_order_id ON ledger_entries (order_id);
+
+CREATE TABLE ledger_drifts
+(
+    id          BIGSERIAL PRIMARY KEY,
+    kind        VARCHAR(16)    NOT NULL,
+    user_id     BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    journal_id  BIGINT,
+    expected    DECIMAL(20, 2) NOT NULL,
+    actual      DECIMAL(20, 2) NOT NULL,
+    detected_at TIMESTAMPTZ    NOT NULL
+);
+
+CREATE INDEX idx_ledger_drifts_detected_at ON ledger_drifts (detected_at);
+
+-- opening balances of existing users, locked margin and insurance fund
+WITH opening AS (SELECT id AS user_id, balance, nextval('ledger_journal_seq') AS journal_id
+                 FROM users
+                 WHERE balance <> 0)
+INSERT
+INTO ledger_entries (journal_id, kind, account, user_id, amount, created_at)
+SELECT journal_id, 'opening_balance', 'user_wallet', user_id, balance, NOW()
+FROM opening
+UNION ALL
+SELECT journal_id, 'opening_balance', 'external', user_id, -balance, NOW()
+FROM opening;
+
+WITH opening AS (SELECT id AS order_id, user_id, margin + extra_margin AS margin, nextval('ledger_journal_seq') AS journal_id
+                 FROM orders
+                 WHERE status IN ('open', 'pending'))
+INSERT
+INTO ledger_entries (journal_id, kind, account, user_id, order_id, amount, created_at)
+SELECT journal_id, 'opening_balance', 'user_margin', user_id, order_id, margin, NOW()
+FROM opening
+UNION ALL
+SELECT journal_id, 'opening_balance', 'external', user_id, order_id, -margin, NOW()
+FROM opening;
+
+WITH opening AS (SELECT balance, nextval('ledger_journal_seq') AS journal_id
+                 FROM insurance_fund
+                 WHERE balance <> 0)
+INSERT
+INTO ledger_entries (journal_id, kind, account, amount, created_at)
+SELECT journal_id, 'opening_balance', 'insurance_fund', balance, NOW()
+FROM opening
+UNION ALL
+SELECT journal_id, 'opening_balance', 'external', -balance, NOW()
+FROM opening;
@@ -0,0 +1,9 @@
+-- events written in transaction of the change they describe, relay publishes and deletes them
+CREATE TABLE outbox
+(
+    id         BIGSERIAL PRIMARY KEY,
+    event_id   UUID        NOT NULL UNIQUE,
+    subject    VARCHAR(64) NOT NULL,
+    payload    JSONB       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
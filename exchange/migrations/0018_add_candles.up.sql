@@ -0,0 +1,12 @@
+CREATE TABLE candles
+(
+    ticker          VARCHAR(20)    NOT NULL,
+    candle_interval VARCHAR(3)     NOT NULL,
+    open_time       TIMESTAMPTZ    NOT NULL,
+    open            DECIMAL(20, 8) NOT NULL,
+    high            DECIMAL(20, 8) NOT NULL,
+    low             DECIMAL(20, 8) NOT NULL,
+    close           DECIMAL(20, 8) NOT NULL,
+    volume          DECIMAL(30, 8) NOT NULL DEFAULT 0,
+    PRIMARY KEY (ticker, candle_interval, open_time)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_user_ticker_opened;
+
+ALTER TABLE orders
+    DROP COLUMN opened_at,
+    DROP COLUMN closed_at;
@@ -0,0 +1,16 @@
+-- created_at of limit order is placement time, opened_at is fill time
+ALTER TABLE orders
+    ADD COLUMN opened_at TIMESTAMPTZ,
+    ADD COLUMN closed_at TIMESTAMPTZ;
+
+UPDATE orders
+SET opened_at = created_at
+WHERE status IN ('open', 'closed', 'liquidated');
+
+UPDATE orders o
+SET closed_at = l.created_at
+FROM (SELECT order_id, MAX(created_at) AS created_at FROM ledger_entries GROUP BY order_id) l
+WHERE l.order_id = o.id
+  AND o.status IN ('closed', 'liquidated');
+
+CREATE INDEX idx_orders_user_ticker_opened ON orders (user_id, ticker, opened_at);
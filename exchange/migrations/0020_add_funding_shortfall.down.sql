@@ -0,0 +1,2 @@
+ALTER TABLE funding_payments
+    DROP COLUMN shortfall;
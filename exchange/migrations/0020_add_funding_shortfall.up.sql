@@ -0,0 +1,3 @@
+-- shortfall is part of funding payment user's balance couldn't cover, it is not charged
+ALTER TABLE funding_payments
+    ADD COLUMN shortfall DECIMAL(20, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- pairs with orders are kept, deleting them would cascade to orders
+DELETE
+FROM trading_pairs tp
+WHERE tp.quote_asset = 'USDT'
+  AND tp.base_asset IN ('BTC', 'ETH', 'SOL', 'BNB', 'LTC', 'XRP', 'ADA', 'DOGE', 'DOT', 'AVAX',
+                        'MATIC', 'LINK', 'UNI', 'ATOM', 'XLM', 'VET', 'FIL', 'TEST')
+  AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.pair_id = tp.id);
@@ -0,0 +1,21 @@
+-- pairs of binance_http_client.streams and test pair, chart lists only known pairs
+INSERT INTO trading_pairs (base_asset, quote_asset)
+VALUES ('BTC', 'USDT'),
+       ('ETH', 'USDT'),
+       ('SOL', 'USDT'),
+       ('BNB', 'USDT'),
+       ('LTC', 'USDT'),
+       ('XRP', 'USDT'),
+       ('ADA', 'USDT'),
+       ('DOGE', 'USDT'),
+       ('DOT', 'USDT'),
+       ('AVAX', 'USDT'),
+       ('MATIC', 'USDT'),
+       ('LINK', 'USDT'),
+       ('UNI', 'USDT'),
+       ('ATOM', 'USDT'),
+       ('XLM', 'USDT'),
+       ('VET', 'USDT'),
+       ('FIL', 'USDT'),
+       ('TEST', 'USDT')
+ON CONFLICT ON CONSTRAINT unique_pair DO NOTHING;
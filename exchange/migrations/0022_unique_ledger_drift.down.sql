@@ -0,0 +1,2 @@
+ALTER TABLE ledger_drifts
+    DROP CONSTRAINT IF EXISTS unique_drift;
@@ -0,0 +1,13 @@
+-- drift which persists between reconciliations is recorded once, when it is first detected
+DELETE
+FROM ledger_drifts d
+    USING ledger_drifts first
+WHERE first.kind = d.kind
+  AND first.user_id IS NOT DISTINCT FROM d.user_id
+  AND first.journal_id IS NOT DISTINCT FROM d.journal_id
+  AND first.expected = d.expected
+  AND first.actual = d.actual
+  AND first.id < d.id;
+
+ALTER TABLE ledger_drifts
+    ADD CONSTRAINT unique_drift UNIQUE NULLS NOT DISTINCT (kind, user_id, journal_id, expected, actual);
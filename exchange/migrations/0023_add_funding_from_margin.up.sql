@@ -0,0 +1,4 @@
+-- from_margin is part of funding payment taken from position's extra margin after balance ran out,
+-- shortfall left after it is paid by insurance fund
+ALTER TABLE funding_payments
+    ADD COLUMN from_margin DECIMAL(20, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"Exchange/internal/domain/models"
+	"Exchange/internal/domain/models/transport"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type AdminHandler struct {
+	log              *slog.Logger
+	insuranceService insuranceService
+	ledgerService    ledgerService
+	authMiddleware   func(http.Handler) http.Handler
+	adminMiddleware  func(http.Handler) http.Handler
+}
+
+type insuranceService interface {
+	GetFund(ctx context.Context, limit, offset int) (models.InsuranceFund, []models.InsuranceFundEntry, error)
+}
+
+type ledgerService interface {
+	GetDrifts(ctx context.Context, limit, offset int) ([]models.LedgerDrift, error)
+}
+
+func NewAdminHandler(log *slog.Logger,
+	insuranceService insuranceService,
+	ledgerService ledgerService,
+	authMiddleware func(http.Handler) http.Handler,
+	adminMiddleware func(http.Handler) http.Handler) *AdminHandler {
+	return &AdminHandler{
+		log:              log,
+		insuranceService: insuranceService,
+		ledgerService:    ledgerService,
+		authMiddleware:   authMiddleware,
+		adminMiddleware:  adminMiddleware,
+	}
+}
+
+func (a *AdminHandler) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+
+	router.Route("/api/admin", func(router chi.Router) {
+		router.Use(a.authMiddleware)
+		router.Use(a.adminMiddleware)
+
+		router.Get("/insurance-fund", a.GetInsuranceFund)
+		router.Get("/ledger/drifts", a.GetLedgerDrifts)
+	})
+
+	return router
+}
+
+func (a *AdminHandler) GetInsuranceFund(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, ok := pageParams(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid limit or offset",
+		})
+		return
+	}
+
+	fund, history, err := a.insuranceService.GetFund(r.Context(), limit, offset)
+	if err != nil {
+		a.log.Error("Error getting insurance fund", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get insurance fund",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.InsuranceFundResponse{
+		InsuranceFund: fund,
+		History:       history,
+	})
+}
+
+func (a *AdminHandler) GetLedgerDrifts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, ok := pageParams(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid limit or offset",
+		})
+		return
+	}
+
+	drifts, err := a.ledgerService.GetDrifts(r.Context(), limit, offset)
+	if err != nil {
+		a.log.Error("Error getting ledger drifts", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get ledger drifts",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.LedgerDriftsResponse{
+		Drifts: drifts,
+	})
+}
+
+// pageParams reads optional limit and offset query params
+func pageParams(r *http.Request) (int, int, bool) {
+	var limit, offset int
+	for name, dst := range map[string]*int{"limit": &limit, "offset": &offset} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return 0, 0, false
+		}
+		*dst = v
+	}
+	return limit, offset, true
+}
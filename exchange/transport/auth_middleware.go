@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"Exchange/internal/domain/models/transport"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type ctxKey string
+
+const userIDKey ctxKey = "user_id"
+
+type authenticator interface {
+	Authenticate(ctx context.Context, accessToken string) (int64, error)
+}
+
+// NewAuthMiddleware checks bearer access token and puts its owner id into request context
+func NewAuthMiddleware(log *slog.Logger, auth authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				writeUnauthorized(w)
+				return
+			}
+
+			userID, err := auth.Authenticate(r.Context(), token)
+			if err != nil {
+				log.Info("Authentication failed", "error", err)
+				writeUnauthorized(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NewAdminMiddleware lets through only users listed in adminIDs, must run after auth middleware
+func NewAdminMiddleware(log *slog.Logger, adminIDs []int64) func(http.Handler) http.Handler {
+	admins := make(map[int64]struct{}, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok {
+				writeUnauthorized(w)
+				return
+			}
+			if _, ok := admins[userID]; !ok {
+				log.Info("Admin access denied", "user_id", userID)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(transport.ErrorResponse{
+					Error: "Forbidden",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserIDFromContext returns id of authenticated user
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey).(int64)
+	return userID, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(header, prefix))
+	return token, token != ""
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(transport.ErrorResponse{
+		Error: "Unauthorized",
+	})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"Exchange/internal/domain/models"
+	"Exchange/internal/domain/models/transport"
+	"Exchange/internal/services/candles"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type CandleHandler struct {
+	log           *slog.Logger
+	candleService candleService
+}
+
+type candleService interface {
+	GetCandles(ctx context.Context, ticker, interval string, from, to time.Time, limit int) ([]models.Candle, error)
+}
+
+func NewCandleHandler(log *slog.Logger, candleService candleService) *CandleHandler {
+	return &CandleHandler{
+		log:           log,
+		candleService: candleService,
+	}
+}
+
+func (h *CandleHandler) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+
+	router.Route("/api/candles", func(router chi.Router) {
+		router.Get("/", h.GetCandles)
+	})
+
+	return router
+}
+
+// GetCandles returns bars of ?ticker=BTC/USDT&interval=1m opened in [from, to) (RFC3339)
+func (h *CandleHandler) GetCandles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ticker := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("ticker")))
+	interval := r.URL.Query().Get("interval")
+	if ticker == "" || interval == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "ticker and interval are required",
+		})
+		return
+	}
+
+	from, to, ok := periodParams(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid from or to, RFC3339 expected",
+		})
+		return
+	}
+	limit, _, ok := pageParams(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid limit",
+		})
+		return
+	}
+
+	bars, err := h.candleService.GetCandles(r.Context(), ticker, interval, from, to, limit)
+	if err != nil {
+		h.log.Error("Error getting candles", "error", err, "ticker", ticker, "interval", interval)
+
+		switch {
+		case errors.Is(err, candles.ErrInvalidInterval):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Invalid interval, expected 1m, 5m, 15m, 1h or 1d",
+			})
+		case errors.Is(err, candles.ErrInvalidPeriod):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "from must be before to",
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Failed to get candles",
+			})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.CandlesResponse{
+		Ticker:   ticker,
+		Interval: interval,
+		Candles:  bars,
+	})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"Exchange/internal/domain/models"
+	"Exchange/internal/domain/models/transport"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+)
+
+type FundingHandler struct {
+	log            *slog.Logger
+	fundingService fundingService
+	authMiddleware func(http.Handler) http.Handler
+}
+
+type fundingService interface {
+	GetPayments(ctx context.Context, userId int64, limit, offset int) ([]models.FundingPayment, error)
+}
+
+func NewFundingHandler(log *slog.Logger,
+	fundingService fundingService,
+	authMiddleware func(http.Handler) http.Handler) *FundingHandler {
+	return &FundingHandler{
+		log:            log,
+		fundingService: fundingService,
+		authMiddleware: authMiddleware,
+	}
+}
+
+func (f *FundingHandler) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+
+	router.Route("/api/funding", func(router chi.Router) {
+		router.Use(f.authMiddleware)
+
+		router.Get("/payments", f.GetPayments)
+	})
+
+	return router
+}
+
+func (f *FundingHandler) GetPayments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, ok := pageParams(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid limit or offset",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	payments, err := f.fundingService.GetPayments(r.Context(), userID, limit, offset)
+	if err != nil {
+		f.log.Error("Error getting funding payments", "error", err, "userId", userID)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get funding payments",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.FundingPaymentsResponse{
+		Payments: payments,
+	})
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"Exchange/internal/domain/models"
+	"Exchange/internal/domain/models/transport"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+)
+
+type HealthHandler struct {
+	log     *slog.Logger
+	monitor marketDataMonitor
+}
+
+type marketDataMonitor interface {
+	Health(ctx context.Context) []models.PairHealth
+}
+
+func NewHealthHandler(log *slog.Logger, monitor marketDataMonitor) *HealthHandler {
+	return &HealthHandler{
+		log:     log,
+		monitor: monitor,
+	}
+}
+
+func (h *HealthHandler) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+
+	router.Route("/api/health", func(router chi.Router) {
+		router.Get("/", h.GetHealth)
+	})
+
+	return router
+}
+
+// GetHealth reports freshness of prices of every pair. Service is degraded
+// while any pair is stale, trading of stale pair is halted
+func (h *HealthHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pairs := h.monitor.Health(r.Context())
+	status := healthOK
+	for _, pair := range pairs {
+		if pair.Stale {
+			h.log.Warn("Stale market data", "ticker", pair.Ticker)
+			status = healthDegraded
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.HealthResponse{
+		Status: status,
+		Pairs:  pairs,
+	})
+}
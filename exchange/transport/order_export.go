@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"Exchange/internal/domain/models"
+	"Exchange/internal/domain/models/transport"
+	"Exchange/internal/services/trade"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// exportFlushRows is how many rows are buffered before they are pushed to client
+const exportFlushRows = 1000
+
+var orderExportHeader = []string{
+	"id", "ticker", "type", "status", "leverage", "margin", "extra_margin",
+	"entry_price", "close_price", "realized_pnl", "fees", "funding", "created_at", "closed_at",
+}
+
+type orderExportWriter interface {
+	Write(order models.OrderExport) error
+	Flush() error
+}
+
+// exportStream remembers whether anything has reached client,
+// until then failed export can still be answered with error status
+type exportStream struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (s *exportStream) Write(p []byte) (int, error) {
+	s.started = true
+	return s.w.Write(p)
+}
+
+type csvOrderWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (c *csvOrderWriter) Write(o models.OrderExport) error {
+	if !c.header {
+		c.header = true
+		if err := c.w.Write(orderExportHeader); err != nil {
+			return err
+		}
+	}
+
+	closePrice := ""
+	if o.ClosePrice != nil {
+		closePrice = o.ClosePrice.String()
+	}
+	closedAt := ""
+	if o.ClosedAt != nil {
+		closedAt = o.ClosedAt.Format(time.RFC3339)
+	}
+	return c.w.Write([]string{
+		o.Id.String(),
+		o.Ticker,
+		string(o.Type),
+		string(o.Status),
+		strconv.Itoa(int(o.Leverage)),
+		o.Margin.String(),
+		o.ExtraMargin.String(),
+		o.EntryPrice.String(),
+		closePrice,
+		o.RealizedPnl.String(),
+		o.Fees.String(),
+		o.Funding.String(),
+		o.CreatedAt.Format(time.RFC3339),
+		closedAt,
+	})
+}
+
+func (c *csvOrderWriter) Flush() error {
+	if !c.header {
+		c.header = true
+		if err := c.w.Write(orderExportHeader); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonOrderWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONOrderWriter(stream *exportStream) *ndjsonOrderWriter {
+	buf := bufio.NewWriter(stream)
+	return &ndjsonOrderWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (n *ndjsonOrderWriter) Write(o models.OrderExport) error {
+	return n.enc.Encode(o)
+}
+
+func (n *ndjsonOrderWriter) Flush() error {
+	return n.buf.Flush()
+}
+
+// ExportUserOrders streams user's order history as csv (default) or ndjson:
+// ?format=ndjson&ticker=BTC/USDT&from=2024-12-01T00:00:00Z&to=2025-01-01T00:00:00Z
+func (h *TradeHandler) ExportUserOrders(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := periodParams(r)
+	if !ok {
+		writeExportError(w, http.StatusBadRequest, "Invalid from or to, RFC3339 expected")
+		return
+	}
+	filter := models.OrderExportFilter{
+		Ticker: r.URL.Query().Get("ticker"),
+		From:   from,
+		To:     to,
+	}
+
+	stream := &exportStream{w: w}
+	var writer orderExportWriter
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="orders.csv"`)
+		writer = &csvOrderWriter{w: csv.NewWriter(stream)}
+	case "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="orders.ndjson"`)
+		writer = newNDJSONOrderWriter(stream)
+	default:
+		writeExportError(w, http.StatusBadRequest, "Invalid format, csv or ndjson expected")
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	controller := http.NewResponseController(w)
+	rows := 0
+	err := h.tradeService.ExportUserOrders(r.Context(), userID, filter, func(order models.OrderExport) error {
+		if err := writer.Write(order); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows != 0 {
+			return nil
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		return controller.Flush()
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		return
+	}
+
+	h.log.Error("Error exporting orders", "error", err, "userId", userID, "rows", rows)
+	if stream.started {
+		// status is already sent, client sees truncated export
+		return
+	}
+	if errors.Is(err, trade.ErrInvalidPeriod) {
+		writeExportError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+	writeExportError(w, http.StatusInternalServerError, "Failed to export orders")
+}
+
+func writeExportError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Del("Content-Disposition")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(transport.ErrorResponse{
+		Error: msg,
+	})
+}
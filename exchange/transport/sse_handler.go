@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"Exchange/internal/domain/models/transport"
+	"Exchange/internal/gateway"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sseRetry is reconnection delay suggested to client, in milliseconds
+const sseRetry = 3000
+
+type SSEHandler struct {
+	log          *slog.Logger
+	streamer     streamer
+	auth         authenticator
+	pingInterval time.Duration
+}
+
+type streamer interface {
+	Open(userId int64, tickers []string, lastEventId uint64) (*gateway.Stream, error)
+}
+
+func NewSSEHandler(log *slog.Logger, streamer streamer, auth authenticator, pingInterval time.Duration) *SSEHandler {
+	return &SSEHandler{
+		log:          log,
+		streamer:     streamer,
+		auth:         auth,
+		pingInterval: pingInterval,
+	}
+}
+
+func (h *SSEHandler) Routes() chi.Router {
+	router := chi.NewRouter()
+
+	router.Route("/api/sse", func(router chi.Router) {
+		router.Get("/", h.Stream)
+	})
+
+	return router
+}
+
+// Stream sends prices of ?tickers=BTC/USDT,ETH/USDT and events of authenticated user
+// as server-sent events. User events carry JetStream sequence as id, so reconnect
+// with Last-Event-ID header (or ?last_event_id=) replays events missed in between
+func (h *SSEHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(r)
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+
+	var userID int64
+	if token != "" {
+		var err error
+		userID, err = h.auth.Authenticate(r.Context(), token)
+		if err != nil {
+			h.log.Info("SSE authentication failed", "error", err)
+			writeUnauthorized(w)
+			return
+		}
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastSeq uint64
+	if lastEventID != "" {
+		seq, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeSSEError(w, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		lastSeq = seq
+	}
+
+	var tickers []string
+	if raw := r.URL.Query().Get("tickers"); raw != "" {
+		tickers = strings.Split(raw, ",")
+	}
+
+	stream, err := h.streamer.Open(userID, tickers, lastSeq)
+	if err != nil {
+		h.log.Error("Failed to open stream", "error", err, "userId", userID)
+		if errors.Is(err, gateway.ErrTooManySubscriptions) {
+			writeSSEError(w, http.StatusBadRequest, "Too many tickers")
+			return
+		}
+		writeSSEError(w, http.StatusInternalServerError, "Failed to open stream")
+		return
+	}
+	defer stream.Close()
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginx must not buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+	if err := controller.Flush(); err != nil {
+		h.log.Error("SSE flush is not supported", "error", err)
+		return
+	}
+
+	ping := time.NewTicker(h.pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case u := <-stream.Updates():
+			if u.Id != 0 {
+				fmt.Fprintf(w, "id: %d\n", u.Id)
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", u.Event, u.Data)
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case <-stream.Done():
+			// client was too slow, it resumes from Last-Event-ID after retry
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSEError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(transport.ErrorResponse{
+		Error: msg,
+	})
+}
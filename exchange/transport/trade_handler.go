@@ -3,6 +3,8 @@
 import (
 	"Exchange/internal/domain/models"
 	"Exchange/internal/domain/models/transport"
+	"Exchange/internal/marketdata"
+	"Exchange/internal/services/order"
 	"Exchange/internal/services/trade"
 	"Exchange/internal/storage/postgres"
 	"context"
@@ -18,9 +20,10 @@ import (
 )
 
 type TradeHandler struct {
-	log          *slog.Logger
-	tradeService tradeService
-	validate     *validator.Validate
+	log            *slog.Logger
+	tradeService   tradeService
+	validate       *validator.Validate
+	authMiddleware func(http.Handler) http.Handler
 }
 
 type tradeService interface {
@@ -29,16 +32,44 @@ type tradeService interface {
 		ticker string,
 		orderType models.OrderType,
 		margin decimal.Decimal,
-		leverage uint8) (uuid.UUID, error)
-	CloseTradeDeal(ctx context.Context, orderId uuid.UUID, ticker string) (uuid.UUID, error)
+		leverage uint8,
+		stops models.Stops) (uuid.UUID, error)
+	PlaceLimitOrder(ctx context.Context,
+		userId int64,
+		ticker string,
+		orderType models.OrderType,
+		margin decimal.Decimal,
+		leverage uint8,
+		limitPrice decimal.Decimal,
+		stops models.Stops) (uuid.UUID, error)
+	UpdateOrderStops(ctx context.Context, userId int64, orderId uuid.UUID, stops models.Stops) (uuid.UUID, error)
+	SetTrailingStop(ctx context.Context, userId int64, orderId uuid.UUID, ts *models.TrailingStop) (uuid.UUID, error)
+	PartialCloseTradeDeal(ctx context.Context,
+		userId int64,
+		orderId uuid.UUID,
+		fraction decimal.Decimal,
+		notional decimal.Decimal) (models.OrderClose, decimal.Decimal, error)
+	GetOrderCloses(ctx context.Context, userId int64, orderId uuid.UUID) ([]models.OrderClose, error)
+	GetOrderFees(ctx context.Context, userId int64, orderId uuid.UUID) ([]models.OrderFee, error)
+	AddMargin(ctx context.Context, userId int64, orderId uuid.UUID, amount decimal.Decimal) (models.Order, error)
+	WithdrawMargin(ctx context.Context, userId int64, orderId uuid.UUID, amount decimal.Decimal) (models.Order, error)
+	CancelLimitOrder(ctx context.Context, userId int64, orderId uuid.UUID) (uuid.UUID, error)
+	CloseTradeDeal(ctx context.Context, userId int64, orderId uuid.UUID) (uuid.UUID, error)
 	GetUserOrders(ctx context.Context, id int64) ([]models.Order, error)
+	GetUserOrder(ctx context.Context, userId int64, orderId uuid.UUID) (models.Order, error)
+	GetPortfolio(ctx context.Context, userId int64) (models.Portfolio, error)
+	ExportUserOrders(ctx context.Context, userId int64, filter models.OrderExportFilter, fn func(models.OrderExport) error) error
 }
 
-func NewTradeHandler(log *slog.Logger, tradeService tradeService, validate *validator.Validate) *TradeHandler {
+func NewTradeHandler(log *slog.Logger,
+	tradeService tradeService,
+	validate *validator.Validate,
+	authMiddleware func(http.Handler) http.Handler) *TradeHandler {
 	return &TradeHandler{
-		log:          log,
-		tradeService: tradeService,
-		validate:     validate,
+		log:            log,
+		tradeService:   tradeService,
+		validate:       validate,
+		authMiddleware: authMiddleware,
 	}
 }
 
@@ -48,11 +79,23 @@ func (t *TradeHandler) Routes() chi.Router {
 
 	router.Route("/api/trade", func(router chi.Router) {
 		router.Group(func(routerWithAuth chi.Router) {
-			// routerWithAuth.Use(h.authMiddleware)
+			routerWithAuth.Use(t.authMiddleware)
 
 			routerWithAuth.Post("/open", t.PostOpenTrade)
 			routerWithAuth.Post("/close", t.PostCloseTrade)
+			routerWithAuth.Post("/close/partial", t.PostPartialClose)
+			routerWithAuth.Post("/margin/add", t.PostAddMargin)
+			routerWithAuth.Post("/margin/withdraw", t.PostWithdrawMargin)
+			routerWithAuth.Post("/limit", t.PostLimitOrder)
+			routerWithAuth.Post("/cancel", t.PostCancelOrder)
+			routerWithAuth.Post("/stops", t.PostUpdateStops)
+			routerWithAuth.Post("/trailing", t.PostTrailingStop)
 			routerWithAuth.Post("/orders", t.GetUserOrders)
+			routerWithAuth.Get("/orders/export", t.ExportUserOrders)
+			routerWithAuth.Get("/portfolio", t.GetPortfolio)
+			routerWithAuth.Get("/orders/{id}", t.GetUserOrder)
+			routerWithAuth.Get("/orders/{id}/closes", t.GetOrderCloses)
+			routerWithAuth.Get("/orders/{id}/fees", t.GetOrderFees)
 		})
 	})
 
@@ -81,10 +124,11 @@ func (h *TradeHandler) PostOpenTrade(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slog.Debug(req.Ticker)
-	orderID, err := h.tradeService.OpenTradeDeal(r.Context(), req.UserID, req.Ticker, req.OrderType, req.Margin, req.Leverage)
+	userID, _ := UserIDFromContext(r.Context())
+	stops := models.Stops{StopLoss: req.StopLoss, TakeProfit: req.TakeProfit}
+	orderID, err := h.tradeService.OpenTradeDeal(r.Context(), userID, req.Ticker, req.OrderType, req.Margin, req.Leverage, stops)
 	if err != nil {
-		h.log.Error("Failed to open trade", "error", err, "userId", req.UserID)
+		h.log.Error("Failed to open trade", "error", err, "userId", userID)
 
 		switch {
 		case errors.Is(err, trade.ErrNegativeMargin):
@@ -97,6 +141,16 @@ func (h *TradeHandler) PostOpenTrade(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(transport.ErrorResponse{
 				Error: "Invalid leverage value",
 			})
+		case errors.Is(err, trade.ErrInvalidStops):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Invalid stop-loss or take-profit",
+			})
+		case errors.Is(err, marketdata.ErrMarketDataUnavailable):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Market data unavailable",
+			})
 		default:
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(transport.ErrorResponse{
@@ -112,6 +166,250 @@ func (h *TradeHandler) PostOpenTrade(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *TradeHandler) PostLimitOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req transport.PlaceLimitOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Failed to decode request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid request format",
+		})
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.log.Error("Validation failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid trade parameters",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	stops := models.Stops{StopLoss: req.StopLoss, TakeProfit: req.TakeProfit}
+	orderID, err := h.tradeService.PlaceLimitOrder(r.Context(), userID, req.Ticker, req.OrderType, req.Margin, req.Leverage, req.LimitPrice, stops)
+	if err != nil {
+		h.log.Error("Failed to place limit order", "error", err, "userId", userID)
+
+		switch {
+		case errors.Is(err, trade.ErrNegativeMargin):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Margin must be positive",
+			})
+		case errors.Is(err, trade.ErrInvalidLeverage):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Invalid leverage value",
+			})
+		case errors.Is(err, trade.ErrInvalidStops):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Invalid stop-loss or take-profit",
+			})
+		case errors.Is(err, trade.ErrInvalidLimitPrice):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Limit price must be positive",
+			})
+		case errors.Is(err, order.ErrInsufficientFunds):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Insufficient funds",
+			})
+		case errors.Is(err, marketdata.ErrMarketDataUnavailable):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Market data unavailable",
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Failed to place limit order",
+			})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transport.OpenTradeResponse{
+		OrderID: orderID,
+	})
+}
+
+func (h *TradeHandler) PostCancelOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req transport.CancelOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Failed to decode request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid request format",
+		})
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.log.Error("Validation failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Order ID is required",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	orderID, err := h.tradeService.CancelLimitOrder(r.Context(), userID, req.OrderID)
+	if err != nil {
+		h.log.Error("Failed to cancel order", "error", err, "orderId", req.OrderID)
+
+		if writeOrderAccessError(w, err) {
+			return
+		}
+		if errors.Is(err, postgres.ErrOrderNotPending) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Order is not pending",
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to cancel order",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.CloseTradeResponse{
+		OrderID: orderID,
+	})
+}
+
+func (h *TradeHandler) PostUpdateStops(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req transport.UpdateStopsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Failed to decode request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid request format",
+		})
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.log.Error("Validation failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Order ID is required",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	stops := models.Stops{StopLoss: req.StopLoss, TakeProfit: req.TakeProfit}
+	orderID, err := h.tradeService.UpdateOrderStops(r.Context(), userID, req.OrderID, stops)
+	if err != nil {
+		h.log.Error("Failed to update stops", "error", err, "orderId", req.OrderID)
+
+		if writeOrderAccessError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, trade.ErrInvalidStops):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Invalid stop-loss or take-profit",
+			})
+		case errors.Is(err, postgres.ErrOrderNotActive):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Order is not open or pending",
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Failed to update stops",
+			})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.CloseTradeResponse{
+		OrderID: orderID,
+	})
+}
+
+// PostTrailingStop sets trailing stop on open position, empty type removes it
+func (h *TradeHandler) PostTrailingStop(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req transport.TrailingStopRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Failed to decode request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid request format",
+		})
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.log.Error("Validation failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Order ID and trailing type (percent or absolute) are required",
+		})
+		return
+	}
+
+	var ts *models.TrailingStop
+	if req.Type != "" {
+		ts = &models.TrailingStop{Type: req.Type, Value: req.Value}
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	orderID, err := h.tradeService.SetTrailingStop(r.Context(), userID, req.OrderID, ts)
+	if err != nil {
+		h.log.Error("Failed to set trailing stop", "error", err, "orderId", req.OrderID)
+
+		if writeOrderAccessError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, trade.ErrInvalidTrailing):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Invalid trailing stop distance",
+			})
+		case errors.Is(err, postgres.ErrOrderNotActive):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Order is not open",
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Failed to set trailing stop",
+			})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.CloseTradeResponse{
+		OrderID: orderID,
+	})
+}
+
 func (h *TradeHandler) PostCloseTrade(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -129,19 +427,30 @@ func (h *TradeHandler) PostCloseTrade(w http.ResponseWriter, r *http.Request) {
 		h.log.Error("Validation failed", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
-			Error: "Order ID and ticker are required",
+			Error: "Order ID is required",
 		})
 		return
 	}
 
-	orderID, err := h.tradeService.CloseTradeDeal(r.Context(), req.OrderID, req.Ticker)
+	userID, _ := UserIDFromContext(r.Context())
+	orderID, err := h.tradeService.CloseTradeDeal(r.Context(), userID, req.OrderID)
 	if err != nil {
 		h.log.Error("Failed to close trade", "error", err, "orderId", req.OrderID)
 
-		if errors.Is(err, postgres.ErrOrderNotExists) {
-			w.WriteHeader(http.StatusNotFound)
+		if writeOrderAccessError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, postgres.ErrOrderNotOpen):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Order is not open",
+			})
+			return
+		case errors.Is(err, marketdata.ErrMarketDataUnavailable):
+			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(transport.ErrorResponse{
-				Error: "Order not found",
+				Error: "Market data unavailable",
 			})
 			return
 		}
@@ -159,23 +468,87 @@ func (h *TradeHandler) PostCloseTrade(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (t *TradeHandler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
+func (h *TradeHandler) PostPartialClose(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// 1. Парсим запрос
-	var req transport.GetOrdersRequest
+	var req transport.PartialCloseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		t.log.Error("Error decoding orders request:", err)
+		h.log.Error("Failed to decode request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
-			Error: "Failed to decode request body",
+			Error: "Invalid request format",
 		})
 		return
 	}
 
-	// 2. Валидируем запрос
-	if err := t.validate.Struct(&req); err != nil {
-		t.log.Error("Error validating balance request:", err)
+	if err := h.validate.Struct(req); err != nil {
+		h.log.Error("Validation failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Order ID is required",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	closed, remaining, err := h.tradeService.PartialCloseTradeDeal(r.Context(), userID, req.OrderID, req.Fraction, req.Notional)
+	if err != nil {
+		h.log.Error("Failed to partially close trade", "error", err, "orderId", req.OrderID)
+
+		if writeOrderAccessError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, trade.ErrInvalidCloseAmount), errors.Is(err, postgres.ErrCloseExceedsMargin):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Pass either fraction (0..1) or notional smaller than position",
+			})
+		case errors.Is(err, postgres.ErrOrderNotOpen), errors.Is(err, postgres.ErrOrderChanged):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Order is not open or was changed, retry",
+			})
+		case errors.Is(err, marketdata.ErrMarketDataUnavailable):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Market data unavailable",
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Failed to close trade",
+			})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.PartialCloseResponse{
+		OrderID:         req.OrderID,
+		ClosedMargin:    closed.Margin,
+		ClosePrice:      closed.ClosePrice,
+		RealizedPnl:     closed.RealizedPnl,
+		Fee:             closed.Fee,
+		RemainingMargin: remaining,
+	})
+}
+
+func (h *TradeHandler) PostAddMargin(w http.ResponseWriter, r *http.Request) {
+	h.adjustMargin(w, r, h.tradeService.AddMargin)
+}
+
+func (h *TradeHandler) PostWithdrawMargin(w http.ResponseWriter, r *http.Request) {
+	h.adjustMargin(w, r, h.tradeService.WithdrawMargin)
+}
+
+func (h *TradeHandler) adjustMargin(w http.ResponseWriter, r *http.Request,
+	adjust func(ctx context.Context, userId int64, orderId uuid.UUID, amount decimal.Decimal) (models.Order, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req transport.MarginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Failed to decode request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
 			Error: "Invalid request format",
@@ -183,10 +556,140 @@ func (t *TradeHandler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 3. Получаем баланс
-	orders, err := t.tradeService.GetUserOrders(r.Context(), req.Id)
+	if err := h.validate.Struct(req); err != nil {
+		h.log.Error("Validation failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Order ID and amount are required",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	o, err := adjust(r.Context(), userID, req.OrderID, req.Amount)
+	if err != nil {
+		h.log.Error("Failed to adjust margin", "error", err, "orderId", req.OrderID)
+
+		if writeOrderAccessError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, trade.ErrInvalidMargin), errors.Is(err, postgres.ErrNegativeExtraMargin):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Amount must be positive and not exceed added margin",
+			})
+		case errors.Is(err, trade.ErrWithdrawLiquidates):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Withdrawal would liquidate position",
+			})
+		case errors.Is(err, postgres.ErrInsufficientFunds):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Insufficient funds",
+			})
+		case errors.Is(err, postgres.ErrOrderNotOpen), errors.Is(err, postgres.ErrOrderChanged):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Order is not open or was changed, retry",
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Failed to adjust margin",
+			})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.MarginResponse{
+		OrderID:          o.Id,
+		Margin:           o.Margin,
+		ExtraMargin:      o.ExtraMargin,
+		LiquidationPrice: o.LiquidationPrice,
+	})
+}
+
+func (t *TradeHandler) GetOrderCloses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	closes, err := t.tradeService.GetOrderCloses(r.Context(), userID, orderID)
+	if err != nil {
+		t.log.Error("Error getting order closes", "error", err, "orderId", orderID)
+
+		if writeOrderAccessError(w, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get order closes",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.GetOrderClosesResponse{
+		Closes: closes,
+	})
+}
+
+func (t *TradeHandler) GetOrderFees(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	orderFees, err := t.tradeService.GetOrderFees(r.Context(), userID, orderID)
+	if err != nil {
+		t.log.Error("Error getting order fees", "error", err, "orderId", orderID)
+
+		if writeOrderAccessError(w, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get order fees",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.GetOrderFeesResponse{
+		Fees: orderFees,
+	})
+}
+
+func (t *TradeHandler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 1. Берем пользователя из токена
+	userID, _ := UserIDFromContext(r.Context())
+
+	// 2. Получаем ордера
+	orders, err := t.tradeService.GetUserOrders(r.Context(), userID)
 	if err != nil {
-		t.log.Error("Error getting balance:", "error", err, "userId", req.Id)
+		t.log.Error("Error getting orders", "error", err, "userId", userID)
 
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
@@ -195,9 +698,79 @@ func (t *TradeHandler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 4. Формируем ответ
+	// 3. Формируем ответ
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(transport.GetOrdersResponse{
 		Orders: orders,
 	})
 }
+
+// GetPortfolio returns user's open positions valued at current prices with account totals
+func (t *TradeHandler) GetPortfolio(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := UserIDFromContext(r.Context())
+	portfolio, err := t.tradeService.GetPortfolio(r.Context(), userID)
+	if err != nil {
+		t.log.Error("Error getting portfolio", "error", err, "userId", userID)
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get portfolio",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.PortfolioResponse{
+		Portfolio: portfolio,
+	})
+}
+
+func (t *TradeHandler) GetUserOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	o, err := t.tradeService.GetUserOrder(r.Context(), userID, orderID)
+	if err != nil {
+		t.log.Error("Error getting order", "error", err, "orderId", orderID, "userId", userID)
+
+		if writeOrderAccessError(w, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get order",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(o)
+}
+
+// writeOrderAccessError maps missing and foreign orders to 404, foreign order
+// looks missing so caller can't tell whether order id exists
+func writeOrderAccessError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, postgres.ErrOrderNotExists), errors.Is(err, order.ErrForeignOrder):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Order not found",
+		})
+	default:
+		return false
+	}
+
+	return true
+}
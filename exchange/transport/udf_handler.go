@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"Exchange/internal/domain/models"
+	"Exchange/internal/domain/models/transport"
+	"Exchange/internal/services/chart"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	udfExchange        = "Exchange"
+	udfSymbolType      = "crypto"
+	udfVolumePrecision = 8
+)
+
+type UDFHandler struct {
+	log   *slog.Logger
+	chart chartService
+	auth  authenticator
+}
+
+type chartService interface {
+	ResolveSymbol(ctx context.Context, name string) (string, error)
+	Pricescale(ctx context.Context, ticker string) (int, error)
+	Search(ctx context.Context, query string, limit int) ([]string, error)
+	History(ctx context.Context, ticker, resolution string, from, to time.Time, countback int) ([]models.Candle, time.Time, error)
+	Marks(ctx context.Context, userId int64, ticker string, from, to time.Time) ([]models.ChartMark, error)
+}
+
+func NewUDFHandler(log *slog.Logger, chart chartService, auth authenticator) *UDFHandler {
+	return &UDFHandler{
+		log:   log,
+		chart: chart,
+		auth:  auth,
+	}
+}
+
+// Routes serve TradingView UDF datafeed, its url is udf/api/udf
+func (h *UDFHandler) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+
+	router.Route("/api/udf", func(router chi.Router) {
+		router.Get("/config", h.GetConfig)
+		router.Get("/symbols", h.GetSymbol)
+		router.Get("/search", h.Search)
+		router.Get("/history", h.GetHistory)
+		router.Get("/marks", h.GetMarks)
+		router.Get("/time", h.GetTime)
+	})
+
+	return router
+}
+
+func (h *UDFHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.UDFConfigResponse{
+		SupportsSearch: true,
+		SupportsMarks:  true,
+		SupportsTime:   true,
+		Exchanges: []transport.UDFExchange{
+			{Value: udfExchange, Name: udfExchange, Desc: udfExchange},
+		},
+		SymbolsTypes: []transport.UDFSymbolType{
+			{Name: udfSymbolType, Value: udfSymbolType},
+		},
+		SupportedResolutions: udfResolutions(),
+	})
+}
+
+// GetSymbol resolves ?symbol=BTC/USDT
+func (h *UDFHandler) GetSymbol(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ticker, err := h.chart.ResolveSymbol(r.Context(), r.URL.Query().Get("symbol"))
+	if err != nil {
+		h.writeChartError(w, err)
+		return
+	}
+	pricescale, err := h.chart.Pricescale(r.Context(), ticker)
+	if err != nil {
+		h.log.Error("Error getting pricescale", "error", err, "ticker", ticker)
+		h.writeChartError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.UDFSymbolResponse{
+		Name:                 ticker,
+		Ticker:               ticker,
+		Description:          ticker,
+		Type:                 udfSymbolType,
+		Session:              "24x7",
+		Exchange:             udfExchange,
+		ListedExchange:       udfExchange,
+		Timezone:             "Etc/UTC",
+		Minmov:               1,
+		Pricescale:           pricescale,
+		HasIntraday:          true,
+		HasDaily:             true,
+		SupportedResolutions: udfResolutions(),
+		VolumePrecision:      udfVolumePrecision,
+		DataStatus:           "streaming",
+	})
+}
+
+// Search finds symbols by ?query=BTC&limit=30
+func (h *UDFHandler) Search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, _, ok := pageParams(r)
+	if !ok {
+		writeUDFError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	tickers, err := h.chart.Search(r.Context(), r.URL.Query().Get("query"), limit)
+	if err != nil {
+		h.writeChartError(w, err)
+		return
+	}
+
+	results := make([]transport.UDFSearchResult, 0, len(tickers))
+	for _, ticker := range tickers {
+		results = append(results, transport.UDFSearchResult{
+			Symbol:      ticker,
+			FullName:    udfExchange + ":" + ticker,
+			Description: ticker,
+			Exchange:    udfExchange,
+			Ticker:      ticker,
+			Type:        udfSymbolType,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// GetHistory returns bars of ?symbol=BTC/USDT&resolution=1&from=&to= (unix seconds),
+// &countback= asks for number of bars before to instead of range
+func (h *UDFHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, to, ok := unixPeriodParams(r)
+	if !ok {
+		writeUDFError(w, http.StatusBadRequest, "invalid from or to")
+		return
+	}
+	var countback int
+	if raw := r.URL.Query().Get("countback"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			writeUDFError(w, http.StatusBadRequest, "invalid countback")
+			return
+		}
+		countback = v
+	}
+
+	ticker, err := h.chart.ResolveSymbol(r.Context(), r.URL.Query().Get("symbol"))
+	if err != nil {
+		h.writeChartError(w, err)
+		return
+	}
+	bars, nextTime, err := h.chart.History(r.Context(), ticker, r.URL.Query().Get("resolution"), from, to, countback)
+	if err != nil {
+		h.log.Error("Error getting history", "error", err, "ticker", ticker)
+		h.writeChartError(w, err)
+		return
+	}
+
+	resp := transport.UDFHistoryResponse{S: "ok"}
+	if len(bars) == 0 {
+		resp.S = "no_data"
+		if !nextTime.IsZero() {
+			resp.NextTime = nextTime.Unix()
+		}
+	}
+	for _, bar := range bars {
+		resp.T = append(resp.T, bar.OpenTime.Unix())
+		resp.O = append(resp.O, bar.Open.InexactFloat64())
+		resp.H = append(resp.H, bar.High.InexactFloat64())
+		resp.L = append(resp.L, bar.Low.InexactFloat64())
+		resp.C = append(resp.C, bar.Close.InexactFloat64())
+		resp.V = append(resp.V, bar.Volume.InexactFloat64())
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetMarks returns entries, exits and liquidations of authenticated user on
+// ?symbol=BTC/USDT&from=&to=. Chart can't set headers, so token is also accepted as ?token=.
+// Anonymous user has no marks
+func (h *UDFHandler) GetMarks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, ok := bearerToken(r)
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode([]transport.UDFMark{})
+		return
+	}
+	userID, err := h.auth.Authenticate(r.Context(), token)
+	if err != nil {
+		h.log.Info("UDF authentication failed", "error", err)
+		writeUnauthorized(w)
+		return
+	}
+
+	from, to, ok := unixPeriodParams(r)
+	if !ok {
+		writeUDFError(w, http.StatusBadRequest, "invalid from or to")
+		return
+	}
+	ticker, err := h.chart.ResolveSymbol(r.Context(), r.URL.Query().Get("symbol"))
+	if err != nil {
+		h.writeChartError(w, err)
+		return
+	}
+	marks, err := h.chart.Marks(r.Context(), userID, ticker, from, to)
+	if err != nil {
+		h.log.Error("Error getting marks", "error", err, "userId", userID, "ticker", ticker)
+		h.writeChartError(w, err)
+		return
+	}
+
+	resp := make([]transport.UDFMark, 0, len(marks))
+	for i, mark := range marks {
+		resp = append(resp, udfMark(i, mark))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetTime returns server time in unix seconds as plain text
+func (h *UDFHandler) GetTime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, time.Now().Unix())
+}
+
+func (h *UDFHandler) writeChartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, chart.ErrUnknownSymbol):
+		writeUDFError(w, http.StatusNotFound, "unknown_symbol")
+	case errors.Is(err, chart.ErrInvalidResolution):
+		writeUDFError(w, http.StatusBadRequest, "unsupported resolution")
+	default:
+		writeUDFError(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+func udfMark(i int, mark models.ChartMark) transport.UDFMark {
+	m := transport.UDFMark{
+		Id:             fmt.Sprintf("%s-%s-%d", mark.OrderId, mark.Kind, i),
+		Time:           mark.Time.Unix(),
+		LabelFontColor: "white",
+		MinSize:        14,
+	}
+	switch mark.Kind {
+	case models.MarkEntry:
+		m.Color, m.Label = "green", "B"
+		if mark.Type == models.Short {
+			m.Color, m.Label = "red", "S"
+		}
+		m.Text = fmt.Sprintf("Open %s at %s", mark.Type, mark.Price)
+	case models.MarkExit:
+		m.Color, m.Label = "blue", "C"
+		m.Text = fmt.Sprintf("Close %s at %s", mark.Type, mark.Price)
+	case models.MarkLiquidation:
+		m.Color, m.Label = "red", "L"
+		m.Text = fmt.Sprintf("Liquidated %s at %s", mark.Type, mark.Price)
+	}
+	return m
+}
+
+func udfResolutions() []string {
+	resolutions := make([]string, 0, len(chart.Resolutions))
+	for _, resolution := range chart.Resolutions {
+		resolutions = append(resolutions, resolution.Name)
+	}
+	return resolutions
+}
+
+// unixPeriodParams parses required from and to query params in unix seconds
+func unixPeriodParams(r *http.Request) (time.Time, time.Time, bool) {
+	var from, to time.Time
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		v, err := strconv.ParseInt(r.URL.Query().Get(name), 10, 64)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		*dst = time.Unix(v, 0)
+	}
+
+	return from, to, true
+}
+
+func writeUDFError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(transport.UDFErrorResponse{
+		S:      "error",
+		Errmsg: msg,
+	})
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -18,9 +20,10 @@ import (
 )
 
 type UserHandler struct {
-	log         *slog.Logger
-	userService userService
-	validate    *validator.Validate
+	log            *slog.Logger
+	userService    userService
+	validate       *validator.Validate
+	authMiddleware func(http.Handler) http.Handler
 }
 
 type userService interface {
@@ -29,14 +32,35 @@ type userService interface {
 	IncreaseBalance(ctx context.Context, id int64, increaseAmount decimal.Decimal) (decimal.Decimal, error)
 	DecreaseBalance(ctx context.Context, id int64, decreaseAmount decimal.Decimal) (decimal.Decimal, error)
 	GetUserOrders(ctx context.Context, id int64) ([]models.Order, error)
-	Login(ctx context.Context, email, password string) (int64, string, error)
+	Login(ctx context.Context, email, password string) (models.User, models.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (models.TokenPair, error)
+	Logout(ctx context.Context, accessToken, refreshToken string) error
+	RevokeSessions(ctx context.Context, userId int64) error
+	GetTransactions(ctx context.Context, id int64, filter models.TransactionFilter, limit, offset int) ([]models.Transaction, error)
+	GetStatement(ctx context.Context, id int64, from, to time.Time) (models.Statement, error)
 }
 
-func NewUserHandler(log *slog.Logger, userService userService, validate *validator.Validate) *UserHandler {
+// transactionKinds are ledger kinds which move user's wallet
+var transactionKinds = map[models.LedgerEntryKind]struct{}{
+	models.LedgerOpeningBalance: {},
+	models.LedgerDeposit:        {},
+	models.LedgerWithdrawal:     {},
+	models.LedgerMarginLock:     {},
+	models.LedgerMarginRelease:  {},
+	models.LedgerRealizedPnl:    {},
+	models.LedgerFee:            {},
+	models.LedgerFunding:        {},
+}
+
+func NewUserHandler(log *slog.Logger,
+	userService userService,
+	validate *validator.Validate,
+	authMiddleware func(http.Handler) http.Handler) *UserHandler {
 	return &UserHandler{
-		log:         log,
-		userService: userService,
-		validate:    validate,
+		log:            log,
+		userService:    userService,
+		validate:       validate,
+		authMiddleware: authMiddleware,
 	}
 }
 
@@ -49,13 +73,18 @@ func (h *UserHandler) Routes() chi.Router {
 	router.Route("/api/user", func(router chi.Router) {
 		router.Post("/register", h.PostRegister)
 		router.Post("/login", h.PostLogin)
+		router.Post("/refresh", h.PostRefresh)
 
 		router.Group(func(routerWithAuth chi.Router) {
-			// routerWithAuth.Use(h.authMiddleware) // middleware для аутентификации
+			routerWithAuth.Use(h.authMiddleware) // middleware для аутентификации
 
+			routerWithAuth.Post("/logout", h.PostLogout)
+			routerWithAuth.Post("/sessions/revoke", h.PostRevokeSessions)
 			routerWithAuth.Post("/balance", h.GetBalance)
 			routerWithAuth.Post("/balance/increase", h.PostIncreaseBalance)
 			routerWithAuth.Post("/balance/decrease", h.PostDecreaseBalance)
+			routerWithAuth.Get("/transactions", h.GetTransactions)
+			routerWithAuth.Get("/statement", h.GetStatement)
 		})
 	})
 
@@ -68,7 +97,7 @@ func (h *UserHandler) PostRegister(w http.ResponseWriter, r *http.Request) {
 
 	var regReq transport.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&regReq); err != nil {
-		h.log.Error("Error decoding register request:", err)
+		h.log.Error("Error decoding register request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
 			Error: "Failed to decode request body",
@@ -77,7 +106,7 @@ func (h *UserHandler) PostRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validate.Struct(&regReq); err != nil {
-		h.log.Error("Error validating register request:", err)
+		h.log.Error("Error validating register request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
 			Error: "Invalid email or password format",
@@ -87,7 +116,7 @@ func (h *UserHandler) PostRegister(w http.ResponseWriter, r *http.Request) {
 
 	userID, err := h.userService.RegisterNewUser(r.Context(), regReq.Email, regReq.Password)
 	if err != nil {
-		h.log.Error("Error registering user:", err)
+		h.log.Error("Error registering user", "error", err)
 
 		if errors.Is(err, user.ErrUserAlreadyExists) {
 			w.WriteHeader(http.StatusConflict)
@@ -117,7 +146,7 @@ func (h *UserHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 
 	var loginReq transport.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&loginReq); err != nil {
-		h.log.Error("Error decoding login request:", err)
+		h.log.Error("Error decoding login request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
 			Error: "Failed to decode request body",
@@ -126,7 +155,7 @@ func (h *UserHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.validate.Struct(&loginReq); err != nil {
-		h.log.Error("Error validating login request:", err)
+		h.log.Error("Error validating login request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
 			Error: "Invalid email or password format",
@@ -134,9 +163,9 @@ func (h *UserHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, email, err := h.userService.Login(r.Context(), loginReq.Email, loginReq.Password)
+	account, tokens, err := h.userService.Login(r.Context(), loginReq.Email, loginReq.Password)
 	if err != nil {
-		h.log.Error("Error logging in user:", err)
+		h.log.Error("Error logging in user", "error", err)
 
 		if errors.Is(err, user.ErrInvalidCredentials) {
 			w.WriteHeader(http.StatusUnauthorized)
@@ -154,22 +183,20 @@ func (h *UserHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(struct {
-		ID    int64  `json:"id"`
-		Email string `json:"email"`
-	}{
-		ID:    userID,
-		Email: email,
+	json.NewEncoder(w).Encode(transport.LoginResponse{
+		Id:        account.Id,
+		Email:     account.Email,
+		TokenPair: tokens,
 	})
 }
 
-func (h *UserHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+// PostRefresh exchanges refresh token for a new token pair
+func (h *UserHandler) PostRefresh(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// 1. Парсим запрос
-	var req transport.BalanceRequest
+	var req transport.RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Error("Error decoding balance request:", err)
+		h.log.Error("Error decoding refresh request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
 			Error: "Failed to decode request body",
@@ -177,20 +204,103 @@ func (h *UserHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Валидируем запрос
 	if err := h.validate.Struct(&req); err != nil {
-		h.log.Error("Error validating balance request:", err)
+		h.log.Error("Error validating refresh request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
-			Error: "Invalid request format",
+			Error: "Refresh token is required",
 		})
 		return
 	}
 
-	// 3. Получаем баланс
-	balance, err := h.userService.GetBalance(r.Context(), req.UserID)
+	tokens, err := h.userService.Refresh(r.Context(), req.RefreshToken)
 	if err != nil {
-		h.log.Error("Error getting balance:", "error", err, "userId", req.UserID)
+		h.log.Error("Error refreshing tokens", "error", err)
+
+		if errors.Is(err, user.ErrInvalidToken) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Invalid refresh token",
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to refresh tokens",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// PostLogout revokes current access token and passed refresh token
+func (h *UserHandler) PostLogout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req transport.LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.Error("Error decoding logout request", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Failed to decode request body",
+			})
+			return
+		}
+	}
+
+	accessToken, _ := bearerToken(r)
+	if err := h.userService.Logout(r.Context(), accessToken, req.RefreshToken); err != nil {
+		h.log.Error("Error logging out user", "error", err)
+
+		if errors.Is(err, user.ErrInvalidToken) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "Invalid token",
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to logout",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostRevokeSessions revokes every session of authenticated user
+func (h *UserHandler) PostRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := UserIDFromContext(r.Context())
+	if err := h.userService.RevokeSessions(r.Context(), userID); err != nil {
+		h.log.Error("Error revoking sessions", "error", err, "userId", userID)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to revoke sessions",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 1. Берем пользователя из токена
+	userID, _ := UserIDFromContext(r.Context())
+
+	// 2. Получаем баланс
+	balance, err := h.userService.GetBalance(r.Context(), userID)
+	if err != nil {
+		h.log.Error("Error getting balance", "error", err, "userId", userID)
 
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
@@ -199,10 +309,10 @@ func (h *UserHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 4. Формируем ответ
+	// 3. Формируем ответ
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(transport.BalanceResponse{
-		UserID:  req.UserID,
+		UserID:  userID,
 		Balance: balance,
 	})
 }
@@ -228,14 +338,15 @@ func (h *UserHandler) PostIncreaseBalance(w http.ResponseWriter, r *http.Request
 		h.log.Error("Validation failed", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
-			Error: "Validation failed: userId and amount (positive) are required",
+			Error: "Validation failed: amount (positive) is required",
 		})
 		return
 	}
 
-	newBalance, err := h.userService.IncreaseBalance(r.Context(), req.Id, req.Amount)
+	userID, _ := UserIDFromContext(r.Context())
+	newBalance, err := h.userService.IncreaseBalance(r.Context(), userID, req.Amount)
 	if err != nil {
-		h.log.Error("Balance increase failed", "error", err, "userId", req.Id)
+		h.log.Error("Balance increase failed", "error", err, "userId", userID)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
 			Error: "Failed to increase balance",
@@ -245,7 +356,7 @@ func (h *UserHandler) PostIncreaseBalance(w http.ResponseWriter, r *http.Request
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(transport.BalanceResponse{
-		UserID:  req.Id,
+		UserID:  userID,
 		Balance: newBalance,
 	})
 }
@@ -268,14 +379,15 @@ func (h *UserHandler) PostDecreaseBalance(w http.ResponseWriter, r *http.Request
 		h.log.Error("Validation failed", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(transport.ErrorResponse{
-			Error: "Validation failed: userId and amount (positive) are required",
+			Error: "Validation failed: amount (positive) is required",
 		})
 		return
 	}
 
-	newBalance, err := h.userService.DecreaseBalance(r.Context(), req.Id, req.Amount)
+	userID, _ := UserIDFromContext(r.Context())
+	newBalance, err := h.userService.DecreaseBalance(r.Context(), userID, req.Amount)
 	if err != nil {
-		h.log.Error("Balance decrease failed", "error", err, "userId", req.Id)
+		h.log.Error("Balance decrease failed", "error", err, "userId", userID)
 
 		if errors.Is(err, order.ErrInsufficientFunds) {
 			w.WriteHeader(http.StatusBadRequest)
@@ -294,7 +406,127 @@ func (h *UserHandler) PostDecreaseBalance(w http.ResponseWriter, r *http.Request
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(transport.BalanceResponse{
-		UserID:  req.Id,
+		UserID:  userID,
 		Balance: newBalance,
 	})
 }
+
+// GetTransactions returns user's wallet movements filtered by ?type=deposit,fee&from=&to= (RFC3339)
+func (h *UserHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, ok := pageParams(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid limit or offset",
+		})
+		return
+	}
+
+	from, to, ok := periodParams(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid from or to, RFC3339 expected",
+		})
+		return
+	}
+
+	filter := models.TransactionFilter{From: from, To: to}
+	if raw := r.URL.Query().Get("type"); raw != "" {
+		for _, kind := range strings.Split(raw, ",") {
+			kind := models.LedgerEntryKind(strings.TrimSpace(kind))
+			if _, ok := transactionKinds[kind]; !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(transport.ErrorResponse{
+					Error: "Invalid transaction type: " + string(kind),
+				})
+				return
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	transactions, err := h.userService.GetTransactions(r.Context(), userID, filter, limit, offset)
+	if err != nil {
+		h.log.Error("Error getting transactions", "error", err, "userId", userID)
+
+		if errors.Is(err, user.ErrInvalidPeriod) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "from must be before to",
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get transactions",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.TransactionsResponse{
+		Transactions: transactions,
+	})
+}
+
+// GetStatement summarizes user's wallet over ?from=&to= (RFC3339), to defaults to now
+func (h *UserHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, to, ok := periodParams(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Invalid from or to, RFC3339 expected",
+		})
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	statement, err := h.userService.GetStatement(r.Context(), userID, from, to)
+	if err != nil {
+		h.log.Error("Error getting statement", "error", err, "userId", userID)
+
+		if errors.Is(err, user.ErrInvalidPeriod) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(transport.ErrorResponse{
+				Error: "from must be before to",
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(transport.ErrorResponse{
+			Error: "Failed to get statement",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transport.StatementResponse{
+		Statement: statement,
+	})
+}
+
+// periodParams parses optional from and to query params, missing ones are zero
+func periodParams(r *http.Request) (time.Time, time.Time, bool) {
+	var from, to time.Time
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		*dst = v
+	}
+
+	return from, to, true
+}
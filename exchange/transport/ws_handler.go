@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"log/slog"
+	"net/http"
+)
+
+type WSHandler struct {
+	log      *slog.Logger
+	hub      wsHub
+	auth     authenticator
+	upgrader websocket.Upgrader
+}
+
+type wsHub interface {
+	Serve(conn *websocket.Conn, userId int64)
+}
+
+func NewWSHandler(log *slog.Logger, hub wsHub, auth authenticator) *WSHandler {
+	return &WSHandler{
+		log:  log,
+		hub:  hub,
+		auth: auth,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// CORS is open for every origin, so is WebSocket
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func (h *WSHandler) Routes() chi.Router {
+	router := chi.NewRouter()
+
+	router.Route("/api/ws", func(router chi.Router) {
+		router.Get("/", h.Connect)
+	})
+
+	return router
+}
+
+// Connect upgrades connection to WebSocket. Browsers can't set headers on upgrade,
+// so access token is also accepted as ?token=. Without token client gets only prices
+func (h *WSHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(r)
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+
+	var userID int64
+	if token != "" {
+		var err error
+		userID, err = h.auth.Authenticate(r.Context(), token)
+		if err != nil {
+			h.log.Info("WebSocket authentication failed", "error", err)
+			writeUnauthorized(w)
+			return
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// upgrader has already replied with error status
+		h.log.Info("WebSocket upgrade failed", "error", err)
+		return
+	}
+
+	h.hub.Serve(conn, userID)
+}